@@ -0,0 +1,153 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"context"
+	"fmt"
+	"time"
+)
+
+type ChainStep struct {
+	From   string   `json:"from"`
+	To     string   `json:"to"`
+	Recipe []string `json:"recipe"`
+}
+
+type Chain struct {
+	Path  []string    `json:"path"`
+	Steps []ChainStep `json:"steps"`
+}
+
+type chainLink struct {
+	node     *elementsModel.ElementNode
+	relation *elementsModel.ElementRelation
+}
+
+func StartBidirectional(ctx context.Context, dataset string, sourceName string, targetName string, n int) ([]*Chain, *SearchStats, time.Duration, error) {
+	start := time.Now()
+	s, err := newSearch(ctx, nil, SearchOptions{Dataset: dataset})
+	if err != nil {
+		return nil, &SearchStats{}, 0, err
+	}
+	source, err := s.data.GetElementNode(sourceName)
+	if err != nil {
+		return nil, s.stats, 0, fmt.Errorf("source element '%s' not found", sourceName)
+	}
+	target, err := s.data.GetElementNode(targetName)
+	if err != nil {
+		return nil, s.stats, 0, fmt.Errorf("target element '%s' not found", targetName)
+	}
+
+	chains := s.bidirectional(source, target, n)
+	return chains, s.stats, time.Since(start), ctx.Err()
+}
+
+// bidirectional walks forward from source over Children and backward from
+// target over Parents, always expanding the smaller frontier, and returns up
+// to n chains through the nodes where both searches first meet.
+func (s *search) bidirectional(source *elementsModel.ElementNode, target *elementsModel.ElementNode, n int) []*Chain {
+	if source == nil || target == nil || n <= 0 {
+		return nil
+	}
+
+	if source == target {
+		return []*Chain{{Path: []string{source.Element.Name}, Steps: []ChainStep{}}}
+	}
+
+	forward := map[*elementsModel.ElementNode]*chainLink{source: nil}
+	backward := map[*elementsModel.ElementNode]*chainLink{target: nil}
+	forwardQueue := []*elementsModel.ElementNode{source}
+	backwardQueue := []*elementsModel.ElementNode{target}
+
+	var meetings []*elementsModel.ElementNode
+	depth := 0
+	for len(forwardQueue) > 0 && len(backwardQueue) > 0 && len(meetings) == 0 {
+		if s.cancelled() {
+			return nil
+		}
+		depth++
+		s.stats.reachDepth(depth)
+		s.stats.queueSize(len(forwardQueue) + len(backwardQueue))
+		if len(forwardQueue) <= len(backwardQueue) {
+			nextQueue := []*elementsModel.ElementNode{}
+			for _, current := range forwardQueue {
+				s.stats.visitNode()
+				for _, relation := range current.Children {
+					if len(relation.SourceNodes) < 2 {
+						continue
+					}
+					s.stats.expandRecipe()
+					next := relation.TargetNode
+					if _, seen := forward[next]; seen {
+						continue
+					}
+					forward[next] = &chainLink{node: current, relation: relation}
+					nextQueue = append(nextQueue, next)
+					if _, met := backward[next]; met {
+						meetings = append(meetings, next)
+					}
+				}
+			}
+			forwardQueue = nextQueue
+		} else {
+			nextQueue := []*elementsModel.ElementNode{}
+			for _, current := range backwardQueue {
+				s.stats.visitNode()
+				for _, relation := range current.Parents {
+					if len(relation.SourceNodes) < 2 {
+						continue
+					}
+					s.stats.expandRecipe()
+					for _, prev := range relation.SourceNodes {
+						if _, seen := backward[prev]; seen {
+							continue
+						}
+						backward[prev] = &chainLink{node: current, relation: relation}
+						nextQueue = append(nextQueue, prev)
+						if _, met := forward[prev]; met {
+							meetings = append(meetings, prev)
+						}
+					}
+				}
+			}
+			backwardQueue = nextQueue
+		}
+	}
+
+	var chains []*Chain
+	for _, meeting := range meetings {
+		chains = append(chains, buildChain(meeting, forward, backward))
+		if len(chains) >= n {
+			break
+		}
+	}
+
+	return chains
+}
+
+func buildChain(meeting *elementsModel.ElementNode, forward, backward map[*elementsModel.ElementNode]*chainLink) *Chain {
+	var head []ChainStep
+	path := []string{meeting.Element.Name}
+	for node := meeting; forward[node] != nil; node = forward[node].node {
+		link := forward[node]
+		head = append([]ChainStep{{
+			From:   link.node.Element.Name,
+			To:     node.Element.Name,
+			Recipe: link.relation.Recipe.Ingredients,
+		}}, head...)
+		path = append([]string{link.node.Element.Name}, path...)
+	}
+
+	steps := head
+	for node := meeting; backward[node] != nil; node = backward[node].node {
+		link := backward[node]
+		steps = append(steps, ChainStep{
+			From:   node.Element.Name,
+			To:     link.node.Element.Name,
+			Recipe: link.relation.Recipe.Ingredients,
+		})
+		path = append(path, link.node.Element.Name)
+	}
+
+	return &Chain{Path: path, Steps: steps}
+}
@@ -0,0 +1,122 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type TierCheckpoint struct {
+	Tier            int `json:"tier"`
+	Elements        int `json:"elements"`
+	Discovered      int `json:"discovered"`
+	CompletedAtStep int `json:"completedAtStep"`
+}
+
+type CompletionPlan struct {
+	StartingElements []string         `json:"startingElements"`
+	Steps            []CraftingStep   `json:"steps"`
+	TotalCrafts      int              `json:"totalCrafts"`
+	Checkpoints      []TierCheckpoint `json:"checkpoints"`
+	Unreachable      []string         `json:"unreachable"`
+	Text             string           `json:"text"`
+}
+
+// GetCompletionPlan orders every element reachable from the tier 0 elements
+// so that each one is crafted exactly once, after all of its ingredients.
+// Discovering an element takes at least one craft, so the plan is as short as
+// a full run can be.
+func (ec *ElementController) GetCompletionPlan(dataset string) (*CompletionPlan, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	graph := data.GetElementGraph()
+
+	plan := &CompletionPlan{
+		StartingElements: []string{},
+		Steps:            []CraftingStep{},
+		Checkpoints:      []TierCheckpoint{},
+		Unreachable:      []string{},
+	}
+	for _, node := range graph.Tier0Nodes {
+		plan.StartingElements = append(plan.StartingElements, node.Element.Name)
+	}
+	sort.Strings(plan.StartingElements)
+
+	discovered := make(map[string]bool)
+	for _, name := range plan.StartingElements {
+		discovered[name] = true
+	}
+
+	craftable := craftableFrom(data, plan.StartingElements, true)
+	toCraft := make(map[int]int)
+	for _, discovery := range craftable.Discoveries {
+		plan.Steps = append(plan.Steps, CraftingStep{
+			Step:        len(plan.Steps) + 1,
+			Result:      discovery.Name,
+			Ingredients: discovery.Recipes[0],
+		})
+		discovered[discovery.Name] = true
+		toCraft[discovery.Tier]++
+	}
+	plan.TotalCrafts = len(plan.Steps)
+
+	checkpoints := make(map[int]*TierCheckpoint)
+	for _, node := range graph.AllNodes {
+		tier := node.Element.Tier
+		if checkpoints[tier] == nil {
+			checkpoints[tier] = &TierCheckpoint{Tier: tier}
+		}
+		checkpoints[tier].Elements++
+		if discovered[node.Element.Name] {
+			checkpoints[tier].Discovered++
+		} else {
+			plan.Unreachable = append(plan.Unreachable, node.Element.Name)
+		}
+	}
+	sort.Strings(plan.Unreachable)
+
+	for i, discovery := range craftable.Discoveries {
+		toCraft[discovery.Tier]--
+		if toCraft[discovery.Tier] == 0 {
+			checkpoints[discovery.Tier].CompletedAtStep = plan.Steps[i].Step
+		}
+	}
+	for _, checkpoint := range checkpoints {
+		if checkpoint.Discovered < checkpoint.Elements {
+			checkpoint.CompletedAtStep = -1
+		}
+		plan.Checkpoints = append(plan.Checkpoints, *checkpoint)
+	}
+	sort.Slice(plan.Checkpoints, func(i, j int) bool {
+		return plan.Checkpoints[i].Tier < plan.Checkpoints[j].Tier
+	})
+
+	plan.Text = plan.String()
+	return plan, nil
+}
+
+func (p *CompletionPlan) String() string {
+	var text strings.Builder
+	fmt.Fprintf(&text, "Start with: %s\n", strings.Join(p.StartingElements, ", "))
+	fmt.Fprintf(&text, "Total crafts: %d\n\n", p.TotalCrafts)
+	for _, step := range p.Steps {
+		fmt.Fprintf(&text, "%d. %s = %s\n", step.Step, strings.Join(step.Ingredients, " + "), step.Result)
+	}
+
+	text.WriteString("\nCheckpoints:\n")
+	for _, checkpoint := range p.Checkpoints {
+		status := fmt.Sprintf("complete after step %d", checkpoint.CompletedAtStep)
+		if checkpoint.CompletedAtStep < 0 {
+			status = "incomplete"
+		}
+		fmt.Fprintf(&text, "Tier %d: %d/%d, %s\n", checkpoint.Tier, checkpoint.Discovered, checkpoint.Elements, status)
+	}
+
+	if len(p.Unreachable) > 0 {
+		fmt.Fprintf(&text, "\nUnreachable: %s\n", strings.Join(p.Unreachable, ", "))
+	}
+	return text.String()
+}
@@ -0,0 +1,122 @@
+package elementsController
+
+import (
+	"fmt"
+	"strings"
+)
+
+type CraftingStep struct {
+	Step        int      `json:"step"`
+	Result      string   `json:"result"`
+	Ingredients []string `json:"ingredients"`
+}
+
+type CraftingPlan struct {
+	Target       string         `json:"target"`
+	BaseElements []string       `json:"baseElements"`
+	Steps        []CraftingStep `json:"steps"`
+	Text         string         `json:"text"`
+}
+
+// BuildCraftingPlan flattens a recipe tree into the order a player would
+// craft it: ingredients before results, and every element crafted only the
+// first time it is needed.
+func BuildCraftingPlan(tree *TreeNode) *CraftingPlan {
+	if tree == nil {
+		return nil
+	}
+
+	plan := &CraftingPlan{
+		Target:       tree.Name,
+		BaseElements: []string{},
+		Steps:        []CraftingStep{},
+	}
+	made := make(map[string]bool)
+
+	var visit func(node *TreeNode)
+	visit = func(node *TreeNode) {
+		if made[node.Name] {
+			return
+		}
+		if len(node.Recipe) == 0 {
+			made[node.Name] = true
+			plan.BaseElements = append(plan.BaseElements, node.Name)
+			return
+		}
+
+		ingredients := make([]string, 0, len(node.Recipe))
+		for _, child := range node.Recipe {
+			visit(child)
+			ingredients = append(ingredients, child.Name)
+		}
+
+		made[node.Name] = true
+		plan.Steps = append(plan.Steps, CraftingStep{
+			Step:        len(plan.Steps) + 1,
+			Result:      node.Name,
+			Ingredients: ingredients,
+		})
+	}
+	visit(tree)
+
+	plan.Text = plan.String()
+	return plan
+}
+
+// ValidateTree checks a recipe tree sent by a client before it is planned:
+// every node needs a name and either no ingredients or exactly two. A merged
+// search result, named Root, may hold any number of trees.
+func ValidateTree(tree *TreeNode) error {
+	if tree == nil {
+		return fmt.Errorf("recipe tree is empty")
+	}
+	if tree.Name == "Root" {
+		for i, child := range tree.Recipe {
+			if err := validateTreeNode(child); err != nil {
+				return fmt.Errorf("tree %d: %w", i, err)
+			}
+		}
+		return nil
+	}
+	return validateTreeNode(tree)
+}
+
+func validateTreeNode(node *TreeNode) error {
+	if node == nil {
+		return fmt.Errorf("recipe tree has a null node")
+	}
+	if node.Name == "" {
+		return fmt.Errorf("recipe tree has a node without a name")
+	}
+	if len(node.Recipe) != 0 && len(node.Recipe) != 2 {
+		return fmt.Errorf("'%s' has %d ingredients, want 0 or 2", node.Name, len(node.Recipe))
+	}
+	for _, child := range node.Recipe {
+		if err := validateTreeNode(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildCraftingPlans returns one plan per result tree of a merged search
+// result.
+func BuildCraftingPlans(root *TreeNode) []*CraftingPlan {
+	if root == nil {
+		return nil
+	}
+
+	plans := make([]*CraftingPlan, 0, len(root.Recipe))
+	for _, tree := range root.Recipe {
+		plans = append(plans, BuildCraftingPlan(tree))
+	}
+	return plans
+}
+
+func (p *CraftingPlan) String() string {
+	var text strings.Builder
+	for _, step := range p.Steps {
+		fmt.Fprintf(&text, "%d. %s = %s\n", step.Step, strings.Join(step.Ingredients, " + "), step.Result)
+	}
+	return text.String()
+}
@@ -0,0 +1,29 @@
+package elementsController
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestValidateTree(t *testing.T) {
+	cases := []struct {
+		body  string
+		valid bool
+	}{
+		{`{"Name":"Mud","Recipe":[{"Name":"Earth"},{"Name":"Water"}]}`, true},
+		{`{"Name":"Root","Recipe":[{"Name":"Mud","Recipe":[{"Name":"Earth"},{"Name":"Water"}]},{"Name":"Earth"}]}`, true},
+		{`{"Name":"Mud","Recipe":[null,{"Name":"Earth"}]}`, false},
+		{`{"Name":"Mud","Recipe":[{"Name":""},{"Name":"Earth"}]}`, false},
+		{`{"Name":"Mud","Recipe":[{"Name":"Earth"}]}`, false},
+		{`{"Name":"Root","Recipe":[null]}`, false},
+	}
+	for _, c := range cases {
+		var tree TreeNode
+		if err := json.Unmarshal([]byte(c.body), &tree); err != nil {
+			t.Fatalf("%s: %v", c.body, err)
+		}
+		if err := ValidateTree(&tree); (err == nil) != c.valid {
+			t.Errorf("%s: got error %v, want valid %v", c.body, err, c.valid)
+		}
+	}
+}
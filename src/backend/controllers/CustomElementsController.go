@@ -0,0 +1,31 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+)
+
+// AddElement, UpdateElement and DeleteElement change the custom overlay of a
+// dataset and return what changed in its rebuilt graph.
+func (ec *ElementController) AddElement(dataset string, element elementsModel.Element) (*elementsModel.ReloadDiff, error) {
+	service, err := elementsModel.GetRegistry().Get(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return service.AddElement(element)
+}
+
+func (ec *ElementController) UpdateElement(dataset string, element elementsModel.Element) (*elementsModel.ReloadDiff, error) {
+	service, err := elementsModel.GetRegistry().Get(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return service.UpdateElement(element)
+}
+
+func (ec *ElementController) DeleteElement(dataset string, name string) (*elementsModel.ReloadDiff, error) {
+	service, err := elementsModel.GetRegistry().Get(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return service.DeleteElement(name)
+}
@@ -0,0 +1,266 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"sort"
+	"sync"
+)
+
+// treeOrder ranks the trees kept per element while cycles are allowed; lower
+// ranks are kept first and equal ranks keep the order they were found in.
+type treeOrder func(tree *rankedTree) int
+
+func byRecipeOrder(tree *rankedTree) int { return 0 }
+
+func byHeight(tree *rankedTree) int { return tree.Height }
+
+// maxKeptTrees caps the trees kept for every element below the target, so
+// combining two ingredients stays cheap however many trees are asked for.
+const maxKeptTrees = 100
+
+// cyclicKey is an element together with the required elements its trees
+// still have to contain.
+type cyclicKey struct {
+	node *elementsModel.ElementNode
+	need uint64
+}
+
+// cyclicTrees serves every search mode once cycles are allowed. Following
+// recipes top down could loop forever, so instead the k best trees of every
+// element are relaxed round by round from the trees of the previous round
+// until nothing changes. A tree is never built on an ingredient tree that
+// already crafts the same element. Trees kept in one round are only replaced
+// by better ranked ones, and there are at most as many rounds as elements, so
+// the search always terminates. Like dfs, required elements are split between
+// the ingredients of every recipe, so each element keeps its best trees for
+// every set of required elements it is asked for.
+func (s *search) cyclicTrees(target *elementsModel.ElementNode, k int, order treeOrder, parallel bool) []*rankedTree {
+	if target == nil || k <= 0 || !s.constraints.allowed(target) {
+		return nil
+	}
+	recipes := s.cyclicRecipes(target)
+	if len(recipes) == 0 {
+		tree := &TreeNode{Name: target.Element.Name}
+		if !s.constraints.covers(tree, s.constraints.all) {
+			return nil
+		}
+		return []*rankedTree{{Tree: tree}}
+	}
+
+	kept := min(k, maxKeptTrees)
+	var keys []cyclicKey
+	best := make(map[cyclicKey][]*rankedTree)
+	for _, key := range s.ingredientsOf(target) {
+		node := key.node
+		if node.Element.Tier == 0 || len(node.Parents) == 0 {
+			tree := &TreeNode{Name: node.Element.Name}
+			if s.constraints.covers(tree, key.need) {
+				best[key] = []*rankedTree{{Tree: tree, Crafted: map[string]bool{}}}
+			}
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i].node.Element, keys[j].node.Element
+		if a.Tier != b.Tier {
+			return a.Tier < b.Tier
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return keys[i].need < keys[j].need
+	})
+
+	for round := 1; round <= len(keys); round++ {
+		if s.cancelled() {
+			return nil
+		}
+		s.stats.reachDepth(round)
+		s.stats.queueSize(len(keys))
+
+		trees := make([][]*rankedTree, len(keys))
+		var wg sync.WaitGroup
+		for i, key := range keys {
+			relax := func() {
+				s.stats.visitNode()
+				trees[i] = s.combineRecipes(key.node, s.cyclicRecipes(key.node), best[key], kept, key.need, best, order)
+			}
+			if parallel {
+				s.pool.run(&wg, relax)
+			} else {
+				relax()
+			}
+		}
+		wg.Wait()
+
+		changed := false
+		for i, key := range keys {
+			if !sameRankedTrees(trees[i], best[key]) {
+				changed = true
+			}
+			best[key] = trees[i]
+		}
+		if !changed {
+			break
+		}
+	}
+
+	if s.cancelled() {
+		return nil
+	}
+	return s.combineRecipes(target, recipes, nil, k, s.constraints.all, best, order)
+}
+
+// ingredientsOf returns every allowed element that can appear below target,
+// each with the required elements it is asked for, starting from target with
+// all of them.
+func (s *search) ingredientsOf(target *elementsModel.ElementNode) []cyclicKey {
+	first := cyclicKey{node: target, need: s.constraints.all}
+	seen := map[cyclicKey]bool{first: true}
+	keys := []cyclicKey{first}
+	for i := 0; i < len(keys); i++ {
+		current := keys[i]
+		if current.node != target && (current.node.Element.Tier == 0 || len(current.node.Parents) == 0) {
+			continue
+		}
+		for _, recipe := range s.cyclicRecipes(current.node) {
+			if !s.constraints.allowedRecipe(recipe) {
+				continue
+			}
+			leftNeed, rightNeed, ok := s.constraints.split(current.node, recipe, current.need)
+			if !ok {
+				continue
+			}
+			for j, need := range []uint64{leftNeed, rightNeed} {
+				key := cyclicKey{node: recipe.SourceNodes[j], need: need}
+				if !seen[key] {
+					seen[key] = true
+					keys = append(keys, key)
+				}
+			}
+		}
+	}
+	return keys
+}
+
+func (s *search) cyclicRecipes(node *elementsModel.ElementNode) []*elementsModel.ElementRelation {
+	recipes := make([]*elementsModel.ElementRelation, 0, len(node.Parents)+len(node.CyclicParents))
+	recipes = append(recipes, node.Parents...)
+	return append(recipes, node.CyclicParents...)
+}
+
+// combineRecipes merges into kept the k best trees of node containing need
+// that can be built from the current best trees of its ingredients, skipping
+// any that would craft node again below itself.
+func (s *search) combineRecipes(node *elementsModel.ElementNode, recipes []*elementsModel.ElementRelation, kept []*rankedTree, k int, need uint64, best map[cyclicKey][]*rankedTree, order treeOrder) []*rankedTree {
+	trees := append([]*rankedTree(nil), kept...)
+	name := node.Element.Name
+	for _, recipe := range recipes {
+		if s.cancelled() {
+			return trees
+		}
+		if !s.constraints.allowedRecipe(recipe) {
+			continue
+		}
+		leftNeed, rightNeed, ok := s.constraints.split(node, recipe, need)
+		if !ok {
+			continue
+		}
+		leftTrees := best[cyclicKey{node: recipe.SourceNodes[0], need: leftNeed}]
+		rightTrees := best[cyclicKey{node: recipe.SourceNodes[1], need: rightNeed}]
+		if len(leftTrees) == 0 || len(rightTrees) == 0 {
+			continue
+		}
+
+		s.stats.expandRecipe()
+		for _, left := range leftTrees {
+			for _, right := range rightTrees {
+				if s.cancelled() {
+					return trees
+				}
+				candidate := &rankedTree{
+					Height: max(left.Height, right.Height) + 1,
+				}
+				if len(trees) >= k && order(candidate) >= order(trees[k-1]) {
+					break
+				}
+				if left.Crafted[name] || right.Crafted[name] {
+					s.stats.avoidCycle()
+					continue
+				}
+
+				candidate.Tree = &TreeNode{
+					Name:   name,
+					Recipe: []*TreeNode{left.Tree, right.Tree},
+				}
+				if !s.constraints.covers(candidate.Tree, need) || !s.constraints.withinDepth(candidate.Tree, 0) {
+					continue
+				}
+				candidate.Hash = s.hasher.hash(candidate.Tree)
+				if containsRankedTree(trees, candidate.Hash) {
+					continue
+				}
+
+				candidate.Crafted = map[string]bool{name: true}
+				for crafted := range left.Crafted {
+					candidate.Crafted[crafted] = true
+				}
+				for crafted := range right.Crafted {
+					candidate.Crafted[crafted] = true
+				}
+
+				pos := sort.Search(len(trees), func(i int) bool {
+					return order(trees[i]) > order(candidate)
+				})
+				trees = append(trees, nil)
+				copy(trees[pos+1:], trees[pos:])
+				trees[pos] = candidate
+				if len(trees) > k {
+					trees = trees[:k]
+				}
+			}
+		}
+	}
+	return trees
+}
+
+type rankedTree struct {
+	Tree    *TreeNode
+	Hash    string
+	Height  int
+	Crafted map[string]bool
+}
+
+func containsRankedTree(trees []*rankedTree, hash string) bool {
+	for _, tree := range trees {
+		if tree.Hash == hash {
+			return true
+		}
+	}
+	return false
+}
+
+func sameRankedTrees(a, b []*rankedTree) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Hash != b[i].Hash {
+			return false
+		}
+	}
+	return true
+}
+
+// emitRanked sends the trees found by cyclicTrees in order and returns them.
+func (s *search) emitRanked(ranked []*rankedTree) []*TreeNode {
+	var results []*TreeNode
+	for _, candidate := range ranked {
+		if !s.emit(candidate.Tree) {
+			return results
+		}
+		results = append(results, candidate.Tree)
+	}
+	return results
+}
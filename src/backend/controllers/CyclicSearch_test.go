@@ -0,0 +1,93 @@
+package elementsController
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func containsElement(tree *TreeNode, name string) bool {
+	if tree.Name == name {
+		return true
+	}
+	for _, child := range tree.Recipe {
+		if containsElement(child, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestCyclicSearchHonorsRequiredElements checks that allowing cycles, which
+// only adds recipes, still finds trees through the required elements whenever
+// the tier rule does.
+func TestCyclicSearchHonorsRequiredElements(t *testing.T) {
+	loadTestData(t)
+
+	cases := []struct {
+		target  string
+		require string
+	}{
+		{"Dinosaur", "Sand"},
+		{"Human", "Planet"},
+		{"Mud", "Water"},
+	}
+	for _, c := range cases {
+		for _, mode := range []string{"dfs", "bfs", "shortest"} {
+			opts := SearchOptions{Require: []string{c.require}}
+			plain, _, _, err := StartSearch(context.Background(), mode, false, c.target, 3, opts, nil)
+			if err != nil {
+				t.Fatalf("%s %s: %v", mode, c.target, err)
+			}
+			if len(plain.Recipe) == 0 {
+				t.Fatalf("%s %s: no tree with %s without cycles", mode, c.target, c.require)
+			}
+
+			opts.AllowCycles = true
+			cyclic, _, _, err := StartSearch(context.Background(), mode, false, c.target, 3, opts, nil)
+			if err != nil {
+				t.Fatalf("%s %s with cycles: %v", mode, c.target, err)
+			}
+			if len(cyclic.Recipe) == 0 {
+				t.Errorf("%s %s: no tree with %s once cycles are allowed", mode, c.target, c.require)
+			}
+			for _, tree := range cyclic.Recipe {
+				if !containsElement(tree, c.require) {
+					t.Errorf("%s %s: tree without %s", mode, c.target, c.require)
+				}
+			}
+		}
+	}
+}
+
+// TestCyclicSearchEndsWithoutTrees checks that a search with cycles whose
+// constraints no tree can meet still comes back empty well before its
+// deadline.
+func TestCyclicSearchEndsWithoutTrees(t *testing.T) {
+	loadTestData(t)
+
+	cases := []struct {
+		target string
+		opts   SearchOptions
+	}{
+		{"Mud", SearchOptions{AllowCycles: true, Require: []string{"Sand"}}},
+		{"Grilled cheese", SearchOptions{AllowCycles: true, MaxDepth: 3}},
+	}
+	for _, c := range cases {
+		for _, mode := range []string{"dfs", "bfs", "shortest"} {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			result, _, _, err := StartSearch(ctx, mode, false, c.target, 5, c.opts, nil)
+			timedOut := ctx.Err() != nil
+			cancel()
+			if err != nil {
+				t.Fatalf("%s %s: %v", mode, c.target, err)
+			}
+			if len(result.Recipe) != 0 {
+				t.Errorf("%s %s: got %d trees, want none", mode, c.target, len(result.Recipe))
+			}
+			if timedOut {
+				t.Errorf("%s %s: search ran until the timeout", mode, c.target)
+			}
+		}
+	}
+}
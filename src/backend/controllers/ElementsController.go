@@ -2,11 +2,12 @@ package elementsController
 
 import (
 	elementsModel "backend/models"
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"sync"
-	"sync/atomic"
 	"time"
 )
 
@@ -18,18 +19,115 @@ type TreeNode struct {
 	Recipe []*TreeNode
 }
 
-var NodesVisited int64
+// SearchOptions tunes a single search. MaxDepth and MaxTier of zero mean no
+// limit. AllowCycles also uses the recipes the tier rule drops, detecting
+// cycles instead of relying on the tier order. An empty Dataset searches the
+// default dataset. Workers of zero, or more than the server allows, use one
+// worker per CPU.
+type SearchOptions struct {
+	Dataset     string   `json:"dataset"`
+	Workers     int      `json:"workers"`
+	Exclude     []string `json:"exclude"`
+	Require     []string `json:"require"`
+	MaxDepth    int      `json:"maxDepth"`
+	MaxTier     int      `json:"maxTier"`
+	AllowCycles bool     `json:"allowCycles"`
+}
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+type search struct {
+	ctx         context.Context
+	treeChan    chan *TreeNode
+	stats       *SearchStats
+	hasher      *treeHasher
+	pool        *workerPool
+	constraints *searchConstraints
+	data        *elementsModel.Dataset
+}
+
+// newSearch pins the dataset in use when the search starts, so a reload while
+// it runs does not change the graph under it.
+func newSearch(ctx context.Context, treeChan chan *TreeNode, opts SearchOptions) (*search, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(opts.Dataset)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSearch, err)
+	}
+	constraints, err := newSearchConstraints(opts, data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSearch, err)
+	}
+
+	return &search{
+		ctx:         ctx,
+		treeChan:    treeChan,
+		stats:       &SearchStats{DepthLimit: int64(opts.MaxDepth), TierLimit: int64(opts.MaxTier)},
+		hasher:      &treeHasher{},
+		pool:        newWorkerPool(opts.Workers),
+		constraints: constraints,
+		data:        data,
+	}, nil
+}
+
+// withContext returns a copy of the search that shares its state but stops on
+// ctx, letting a branch abandon work its parent no longer needs.
+func (s *search) withContext(ctx context.Context) *search {
+	branch := *s
+	branch.ctx = ctx
+	return &branch
+}
+
+// emit sends tree to the client unless the search has been cancelled, in
+// which case it returns false and the caller should stop producing trees.
+func (s *search) emit(tree *TreeNode) bool {
+	if s.cancelled() {
+		return false
+	}
+	if s.treeChan == nil {
+		s.stats.emitTree()
+		return !s.cancelled()
+	}
+
+	select {
+	case s.treeChan <- tree:
+		s.stats.emitTree()
+		return true
+	case <-s.ctx.Done():
+		return false
+	}
+}
+
+func (s *search) cancelled() bool {
+	return s.ctx.Err() != nil
+}
+
+func SearchStatus(err error) string {
+	switch {
+	case err == nil:
+		return "completed"
+	case errors.Is(err, context.Canceled):
+		return "cancelled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timed out"
+	default:
+		return "failed"
+	}
+}
 
 func NewElementController(filePath string) (*ElementController, error) {
-	err := elementsModel.GetInstance().Initialize(filePath)
+	err := elementsModel.GetRegistry().Load(elementsModel.DefaultDataset, filePath, elementsModel.LoadOptions{})
 	if err != nil {
 		return nil, err
 	}
 	return &ElementController{}, nil
 }
 
-func (ec *ElementController) GetAllElementsTiers() (map[string][]string, error) {
-	elements := elementsModel.GetInstance().GetAllElements()
+func (ec *ElementController) GetAllElementsTiers(dataset string) (map[string][]string, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	elements := data.GetAllElements()
 
 	tierGroups := make(map[string][]string)
 
@@ -45,76 +143,208 @@ func (ec *ElementController) GetAllElementsTiers() (map[string][]string, error)
 	return tierGroups, nil
 }
 
-func StartDFS(targetName string, n int, treeChan chan *TreeNode) (*TreeNode, int64, time.Duration) {
-	atomic.StoreInt64(&NodesVisited, 0)
+func (ec *ElementController) GetIntegrityReport(dataset string) (*elementsModel.IntegrityReport, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return data.GetIntegrityReport(), nil
+}
+
+func (ec *ElementController) GetTierReport(dataset string, mode string) (*elementsModel.TierReport, error) {
+	tierMode, err := elementsModel.ParseTierMode(mode)
+	if err != nil {
+		return nil, err
+	}
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return data.GetTierReport(tierMode), nil
+}
+
+func (ec *ElementController) Reload(dataset string) (*elementsModel.ReloadDiff, error) {
+	service, err := elementsModel.GetRegistry().Get(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return service.Reload()
+}
+
+func (ec *ElementController) GetChangelog(dataset string) (*elementsModel.Changelog, error) {
+	service, err := elementsModel.GetRegistry().Get(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return service.GetChangelog()
+}
+
+func (ec *ElementController) GetIconPath(dataset string, name string) (string, error) {
+	service, err := elementsModel.GetRegistry().Get(dataset)
+	if err != nil {
+		return "", err
+	}
+	return service.IconPath(name)
+}
+
+type DatasetInfo struct {
+	Name     string `json:"name"`
+	Elements int    `json:"elements"`
+	Default  bool   `json:"default"`
+}
+
+func (ec *ElementController) GetDatasets() []DatasetInfo {
+	datasets := []DatasetInfo{}
+	for _, name := range elementsModel.GetRegistry().Names() {
+		data, err := elementsModel.GetRegistry().Snapshot(name)
+		if err != nil {
+			continue
+		}
+		datasets = append(datasets, DatasetInfo{
+			Name:     name,
+			Elements: len(data.GetAllElements()),
+			Default:  name == elementsModel.DefaultDataset,
+		})
+	}
+	return datasets
+}
+
+func StartSearch(ctx context.Context, mode string, multiThread bool, targetName string, n int, opts SearchOptions, treeChan chan *TreeNode) (*TreeNode, *SearchStats, time.Duration, error) {
+	switch mode {
+	case "shortest":
+		return StartShortest(ctx, targetName, n, opts, treeChan)
+	case "bfs":
+		if multiThread {
+			return StartBFSMulti(ctx, targetName, n, opts, treeChan)
+		}
+		return StartBFS(ctx, targetName, n, opts, treeChan)
+	case "", "dfs":
+		if multiThread {
+			return StartDFSMulti(ctx, targetName, n, opts, treeChan)
+		}
+		return StartDFS(ctx, targetName, n, opts, treeChan)
+	default:
+		return nil, &SearchStats{}, 0, fmt.Errorf("%w: unknown mode '%s'", ErrInvalidSearch, mode)
+	}
+}
+
+func StartDFS(ctx context.Context, targetName string, n int, opts SearchOptions, treeChan chan *TreeNode) (*TreeNode, *SearchStats, time.Duration, error) {
 	start := time.Now()
-	node, err := elementsModel.GetInstance().GetElementNode(targetName)
+	s, err := newSearch(ctx, treeChan, opts)
+	if err != nil {
+		return nil, &SearchStats{}, 0, err
+	}
+	node, err := s.data.GetElementNode(targetName)
 	if err != nil {
-		return nil, NodesVisited, 0
+		return nil, s.stats, 0, err
 	}
 
-	trees := dfs(node, int64(n), treeChan)
-	return mergeTree(trees), NodesVisited, time.Since(start)
+	trees := s.dfs(node, int64(n), s.constraints.all, 0)
+	return mergeTree(trees), s.stats, time.Since(start), ctx.Err()
 }
 
-func StartBFS(targetName string, n int, treeChan chan *TreeNode) (*TreeNode, int64, time.Duration) {
-	atomic.StoreInt64(&NodesVisited, 0)
+func StartBFS(ctx context.Context, targetName string, n int, opts SearchOptions, treeChan chan *TreeNode) (*TreeNode, *SearchStats, time.Duration, error) {
 	start := time.Now()
-	node, err := elementsModel.GetInstance().GetElementNode(targetName)
+	s, err := newSearch(ctx, treeChan, opts)
 	if err != nil {
-		return nil, NodesVisited, 0
+		return nil, &SearchStats{}, 0, err
+	}
+	node, err := s.data.GetElementNode(targetName)
+	if err != nil {
+		return nil, s.stats, 0, err
 	}
 
-	trees := bfs(node, int64(n), treeChan)
-	return mergeTree(trees), NodesVisited, time.Since(start)
+	trees := s.bfs(node, int64(n))
+	return mergeTree(trees), s.stats, time.Since(start), ctx.Err()
 }
 
-func StartDFSMulti(targetName string, n int, treeChan chan *TreeNode) (*TreeNode, int64, time.Duration) {
-	atomic.StoreInt64(&NodesVisited, 0)
+func StartDFSMulti(ctx context.Context, targetName string, n int, opts SearchOptions, treeChan chan *TreeNode) (*TreeNode, *SearchStats, time.Duration, error) {
 	start := time.Now()
-	node, err := elementsModel.GetInstance().GetElementNode(targetName)
+	s, err := newSearch(ctx, treeChan, opts)
+	if err != nil {
+		return nil, &SearchStats{}, 0, err
+	}
+	node, err := s.data.GetElementNode(targetName)
 	if err != nil {
-		return nil, NodesVisited, 0
+		return nil, s.stats, 0, err
 	}
 
-	trees := dfsMulti(node, int64(n), treeChan)
-	return mergeTree(trees), NodesVisited, time.Since(start)
+	trees := s.dfsMulti(node, int64(n), s.constraints.all, 0)
+	return mergeTree(trees), s.stats, time.Since(start), ctx.Err()
 }
 
-func StartBFSMulti(targetName string, n int, treeChan chan *TreeNode) (*TreeNode, int64, time.Duration) {
-	atomic.StoreInt64(&NodesVisited, 0)
+func StartBFSMulti(ctx context.Context, targetName string, n int, opts SearchOptions, treeChan chan *TreeNode) (*TreeNode, *SearchStats, time.Duration, error) {
 	start := time.Now()
-	node, err := elementsModel.GetInstance().GetElementNode(targetName)
+	s, err := newSearch(ctx, treeChan, opts)
 	if err != nil {
-		return nil, NodesVisited, 0
+		return nil, &SearchStats{}, 0, err
+	}
+	node, err := s.data.GetElementNode(targetName)
+	if err != nil {
+		return nil, s.stats, 0, err
 	}
 
-	trees := bfsMulti(node, int64(n), treeChan)
-	return mergeTree(trees), NodesVisited, time.Since(start)
+	trees := s.bfsMulti(node, int64(n))
+	return mergeTree(trees), s.stats, time.Since(start), ctx.Err()
 }
 
-func dfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []*TreeNode {
-	if target == nil {
+func StartShortest(ctx context.Context, targetName string, n int, opts SearchOptions, treeChan chan *TreeNode) (*TreeNode, *SearchStats, time.Duration, error) {
+	start := time.Now()
+	s, err := newSearch(ctx, treeChan, opts)
+	if err != nil {
+		return nil, &SearchStats{}, 0, err
+	}
+	node, err := s.data.GetElementNode(targetName)
+	if err != nil {
+		return nil, s.stats, 0, err
+	}
+
+	trees := s.shortest(node, int64(n))
+	return mergeTree(trees), s.stats, time.Since(start), ctx.Err()
+}
+
+func (s *search) dfs(target *elementsModel.ElementNode, n int64, need uint64, depth int) []*TreeNode {
+	if s.constraints.cycles {
+		return s.emitRanked(s.cyclicTrees(target, int(n), byRecipeOrder, false))
+	}
+	if target == nil || !s.constraints.allowed(target) {
 		return nil
 	}
 
+	s.stats.visitNode()
+	s.stats.reachDepth(depth)
+
 	if len(target.Parents) <= 0 || target.Element.Tier == 0 {
 		node := &TreeNode{
 			Name: target.Element.Name,
 		}
+		if !s.constraints.covers(node, need) {
+			return nil
+		}
 		return []*TreeNode{node}
 	}
+	if !s.constraints.canExpand(depth) {
+		return nil
+	}
 
 	var results []*TreeNode
+	unique := newTreeSet()
 
 	for _, recipe := range target.Parents {
-		if len(recipe.SourceNodes) < 2 {
+		if s.cancelled() {
+			return results
+		}
+		if !s.constraints.allowedRecipe(recipe) {
+			continue
+		}
+		leftNeed, rightNeed, ok := s.constraints.split(target, recipe, need)
+		if !ok {
 			continue
 		}
 
-		atomic.AddInt64(&NodesVisited, 1)
-		leftTrees := dfs(recipe.SourceNodes[0], n, treeChan)
-		rightTrees := dfs(recipe.SourceNodes[1], n, treeChan)
+		s.stats.expandRecipe()
+		leftTrees := s.dfs(recipe.SourceNodes[0], n, leftNeed, depth+1)
+		rightTrees := s.dfs(recipe.SourceNodes[1], n, rightNeed, depth+1)
 
 		for _, left := range leftTrees {
 			for _, right := range rightTrees {
@@ -122,8 +352,13 @@ func dfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []
 					Name:   target.Element.Name,
 					Recipe: []*TreeNode{left, right},
 				}
+				if !s.constraints.covers(node, need) || !unique.add(s.hasher.hash(node)) {
+					continue
+				}
 
-				treeChan <- node
+				if !s.emit(node) {
+					return results
+				}
 
 				results = append(results, node)
 				if len(results) >= int(n) {
@@ -142,136 +377,222 @@ func dfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []
 	return results
 }
 
-func dfsMulti(target *elementsModel.ElementNode, limit int64, treeChan chan *TreeNode) []*TreeNode {
-	if target == nil {
+// dfsMulti expands the ingredients of every recipe in parallel on the worker
+// pool but combines them in recipe order, so it returns the same trees in the
+// same order as dfs. Once limit trees are found the remaining branches are
+// cancelled.
+func (s *search) dfsMulti(target *elementsModel.ElementNode, limit int64, need uint64, depth int) []*TreeNode {
+	if s.constraints.cycles {
+		return s.emitRanked(s.cyclicTrees(target, int(limit), byRecipeOrder, true))
+	}
+	if target == nil || !s.constraints.allowed(target) {
 		return nil
 	}
 
+	s.stats.visitNode()
+	s.stats.reachDepth(depth)
+
 	if len(target.Parents) <= 0 || target.Element.Tier == 0 {
 		node := &TreeNode{
 			Name: target.Element.Name,
 		}
+		if !s.constraints.covers(node, need) {
+			return nil
+		}
 		return []*TreeNode{node}
 	}
+	if !s.constraints.canExpand(depth) {
+		return nil
+	}
+
+	type recipeTrees struct {
+		left  []*TreeNode
+		right []*TreeNode
+		done  chan struct{}
+	}
 
-	var results []*TreeNode
-	var resultsMutex sync.Mutex
 	var wg sync.WaitGroup
+	defer wg.Wait()
+	ctx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+	branch := s.withContext(ctx)
+
+	parts := make([]*recipeTrees, len(target.Parents))
+	for i, recipe := range target.Parents {
+		part := &recipeTrees{done: make(chan struct{})}
+		parts[i] = part
+		if !s.constraints.allowedRecipe(recipe) {
+			close(part.done)
+			continue
+		}
+		leftNeed, rightNeed, ok := s.constraints.split(target, recipe, need)
+		if !ok {
+			close(part.done)
+			continue
+		}
 
-	for _, recipe := range target.Parents {
-		wg.Add(1)
-		go func(recipe *elementsModel.ElementRelation) {
-			defer wg.Done()
-			if len(recipe.SourceNodes) < 2 {
+		s.pool.run(&wg, func() {
+			defer close(part.done)
+			if branch.cancelled() {
 				return
 			}
 
-			atomic.AddInt64(&NodesVisited, 1)
-			leftTrees := dfsMulti(recipe.SourceNodes[0], limit, treeChan)
-			rightTrees := dfsMulti(recipe.SourceNodes[1], limit, treeChan)
+			s.stats.expandRecipe()
+			part.left = branch.dfsMulti(recipe.SourceNodes[0], limit, leftNeed, depth+1)
+			part.right = branch.dfsMulti(recipe.SourceNodes[1], limit, rightNeed, depth+1)
+		})
+	}
 
-			localResults := []*TreeNode{}
-			for _, left := range leftTrees {
-				for _, right := range rightTrees {
-					node := &TreeNode{
-						Name:   target.Element.Name,
-						Recipe: []*TreeNode{left, right},
-					}
+	var results []*TreeNode
+	unique := newTreeSet()
 
-					treeChan <- node
-					localResults = append(localResults, node)
+	for _, part := range parts {
+		<-part.done
+		if s.cancelled() {
+			return results
+		}
 
-					resultsMutex.Lock()
-					if len(results) >= int(limit) {
-						resultsMutex.Unlock()
-						return
-					}
-					resultsMutex.Unlock()
+		for _, left := range part.left {
+			for _, right := range part.right {
+				node := &TreeNode{
+					Name:   target.Element.Name,
+					Recipe: []*TreeNode{left, right},
+				}
+				if !s.constraints.covers(node, need) || !unique.add(s.hasher.hash(node)) {
+					continue
+				}
+
+				if !s.emit(node) {
+					return results
 				}
-			}
 
-			resultsMutex.Lock()
-			results = append(results, localResults...)
-			if len(results) > int(limit) {
-				results = results[:int(limit)]
+				results = append(results, node)
+				if len(results) >= int(limit) {
+					return results
+				}
 			}
-			resultsMutex.Unlock()
-		}(recipe)
+		}
 	}
 
-	wg.Wait()
 	return results
 }
 
-func bfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []*TreeNode {
+// bfsItem is an element waiting in the BFS queue together with the required
+// elements its trees have to contain, split between ingredients like in dfs.
+type bfsItem struct {
+	Element string
+	Need    uint64
+}
+
+// bfsIngredients returns the queue items of the ingredients of recipe for a
+// tree of node containing need, or false when the recipe cannot contain it.
+func (s *search) bfsIngredients(node *elementsModel.ElementNode, recipe *elementsModel.ElementRelation, need uint64) (bfsItem, bfsItem, bool) {
+	if !s.constraints.allowedRecipe(recipe) {
+		return bfsItem{}, bfsItem{}, false
+	}
+	leftNeed, rightNeed, ok := s.constraints.split(node, recipe, need)
+	if !ok {
+		return bfsItem{}, bfsItem{}, false
+	}
+	return bfsItem{Element: recipe.Recipe.Ingredients[0], Need: leftNeed},
+		bfsItem{Element: recipe.Recipe.Ingredients[1], Need: rightNeed}, true
+}
+
+func (s *search) bfs(target *elementsModel.ElementNode, n int64) []*TreeNode {
 	if target == nil {
 		return nil
 	}
+	if s.constraints.cycles {
+		return s.emitRanked(s.cyclicTrees(target, int(n), byHeight, false))
+	}
 
-	type QueueItem struct {
-		Element string
+	if !s.constraints.allowed(target) {
+		return nil
 	}
 
 	if len(target.Parents) <= 0 || target.Element.Tier == 0 {
 		node := &TreeNode{
 			Name: target.Element.Name,
 		}
+		if !s.constraints.covers(node, s.constraints.all) {
+			return nil
+		}
 		return []*TreeNode{node}
 	}
 
-	elementToTree := make(map[string][]*TreeNode)
-	processedElements := make(map[string]bool)
+	elementToTree := make(map[bfsItem][]*TreeNode)
+	processedElements := make(map[bfsItem]bool)
+	emitted := newTreeSet()
 
-	currentQueue := []*QueueItem{}
+	currentQueue := []bfsItem{}
 
 	for _, recipe := range target.Parents {
-		currentQueue = append(currentQueue,
-			&QueueItem{Element: recipe.Recipe.Ingredients[0]},
-			&QueueItem{Element: recipe.Recipe.Ingredients[1]},
-		)
+		left, right, ok := s.bfsIngredients(target, recipe, s.constraints.all)
+		if !ok {
+			continue
+		}
+		currentQueue = append(currentQueue, left, right)
 	}
 
 	var results []*TreeNode
 
+	depth := 0
 	for len(currentQueue) > 0 {
-		nextQueue := []*QueueItem{}
+		depth++
+		s.stats.reachDepth(depth)
+		s.stats.queueSize(len(currentQueue))
+
+		nextQueue := []bfsItem{}
 		for len(currentQueue) > 0 {
+			if s.cancelled() {
+				return results
+			}
+
 			current := currentQueue[0]
 			currentQueue = currentQueue[1:]
 
-			if processedElements[current.Element] {
+			if processedElements[current] {
 				continue
 			}
 
-			currentNode, err := elementsModel.GetInstance().GetElementNode(current.Element)
+			currentNode, err := s.data.GetElementNode(current.Element)
 			if err != nil || currentNode == nil {
 				continue
 			}
+			if !s.constraints.allowed(currentNode) {
+				processedElements[current] = true
+				continue
+			}
 
-			atomic.AddInt64(&NodesVisited, 1)
+			s.stats.visitNode()
 
 			if currentNode.Element.Tier == 0 || len(currentNode.Parents) == 0 {
 				tree := &TreeNode{
 					Name: currentNode.Element.Name,
 				}
 
-				treeChan <- tree
-
-				elementToTree[currentNode.Element.Name] = []*TreeNode{tree}
-				processedElements[currentNode.Element.Name] = true
+				if s.constraints.covers(tree, current.Need) {
+					if emitted.add(s.hasher.hash(tree)) && !s.emit(tree) {
+						return results
+					}
+					elementToTree[current] = []*TreeNode{tree}
+				}
+				processedElements[current] = true
+				continue
 			}
 
 			allReady := true
 			for _, recipe := range currentNode.Parents {
-				if len(recipe.SourceNodes) < 2 {
+				left, right, ok := s.bfsIngredients(currentNode, recipe, current.Need)
+				if !ok {
 					continue
 				}
-				if !processedElements[recipe.Recipe.Ingredients[0]] {
-					nextQueue = append(nextQueue, &QueueItem{Element: recipe.Recipe.Ingredients[0]})
+				if !processedElements[left] {
+					nextQueue = append(nextQueue, left)
 					allReady = false
 				}
-				if !processedElements[recipe.Recipe.Ingredients[1]] {
-					nextQueue = append(nextQueue, &QueueItem{Element: recipe.Recipe.Ingredients[1]})
+				if !processedElements[right] {
+					nextQueue = append(nextQueue, right)
 					allReady = false
 				}
 			}
@@ -282,39 +603,56 @@ func bfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []
 			}
 
 			var trees []*TreeNode
+			unique := newTreeSet()
 			for _, recipe := range currentNode.Parents {
-				leftTrees := elementToTree[recipe.Recipe.Ingredients[0]]
-				rightTrees := elementToTree[recipe.Recipe.Ingredients[1]]
+				left, right, ok := s.bfsIngredients(currentNode, recipe, current.Need)
+				if !ok {
+					continue
+				}
+				leftTrees := elementToTree[left]
+				rightTrees := elementToTree[right]
 				if leftTrees == nil || rightTrees == nil {
 					continue
 				}
+				s.stats.expandRecipe()
 				for _, left := range leftTrees {
 					for _, right := range rightTrees {
 						node := &TreeNode{
 							Name:   currentNode.Element.Name,
 							Recipe: []*TreeNode{left, right},
 						}
+						if !s.constraints.covers(node, current.Need) || !s.constraints.withinDepth(node, 1) || !unique.add(s.hasher.hash(node)) {
+							continue
+						}
 
-						treeChan <- node
+						if emitted.add(s.hasher.hash(node)) && !s.emit(node) {
+							return results
+						}
 
 						trees = append(trees, node)
 					}
 				}
 			}
 			if len(trees) > 0 {
-				elementToTree[currentNode.Element.Name] = trees
-				processedElements[currentNode.Element.Name] = true
+				elementToTree[current] = trees
 			}
+			processedElements[current] = true
 		}
 		currentQueue = nextQueue
 	}
 
+	unique := newTreeSet()
 	for _, recipe := range target.Parents {
-		leftTrees := elementToTree[recipe.Recipe.Ingredients[0]]
-		rightTrees := elementToTree[recipe.Recipe.Ingredients[1]]
+		left, right, ok := s.bfsIngredients(target, recipe, s.constraints.all)
+		if !ok {
+			continue
+		}
+		leftTrees := elementToTree[left]
+		rightTrees := elementToTree[right]
 		if leftTrees == nil || rightTrees == nil {
 			continue
 		}
+		s.stats.expandRecipe()
 
 		for _, left := range leftTrees {
 			for _, right := range rightTrees {
@@ -322,6 +660,9 @@ func bfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []
 					Name:   target.Element.Name,
 					Recipe: []*TreeNode{left, right},
 				}
+				if !s.constraints.covers(node, s.constraints.all) || !s.constraints.withinDepth(node, 0) || !unique.add(s.hasher.hash(node)) {
+					continue
+				}
 				results = append(results, node)
 				if len(results) >= int(n) {
 					return results
@@ -333,103 +674,128 @@ func bfs(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []
 	return results
 }
 
-func bfsMulti(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNode) []*TreeNode {
+func (s *search) bfsMulti(target *elementsModel.ElementNode, n int64) []*TreeNode {
 	if target == nil {
 		return nil
 	}
+	if s.constraints.cycles {
+		return s.emitRanked(s.cyclicTrees(target, int(n), byHeight, true))
+	}
 
-	type QueueItem struct {
-		Element string
+	if !s.constraints.allowed(target) {
+		return nil
 	}
 
 	if len(target.Parents) <= 0 || target.Element.Tier == 0 {
 		node := &TreeNode{
 			Name: target.Element.Name,
 		}
+		if !s.constraints.covers(node, s.constraints.all) {
+			return nil
+		}
 		return []*TreeNode{node}
 	}
 
-	elementToTree := make(map[string][]*TreeNode)
+	elementToTree := make(map[bfsItem][]*TreeNode)
 	var elementToTreeMutex sync.RWMutex
 
-	processedElements := make(map[string]bool)
+	processedElements := make(map[bfsItem]bool)
 	var processedMutex sync.RWMutex
 
-	currentQueue := []*QueueItem{}
+	emitted := newTreeSet()
+
+	currentQueue := []bfsItem{}
 
 	for _, recipe := range target.Parents {
-		currentQueue = append(currentQueue,
-			&QueueItem{Element: recipe.Recipe.Ingredients[0]},
-			&QueueItem{Element: recipe.Recipe.Ingredients[1]},
-		)
+		left, right, ok := s.bfsIngredients(target, recipe, s.constraints.all)
+		if !ok {
+			continue
+		}
+		currentQueue = append(currentQueue, left, right)
 	}
 
 	var results []*TreeNode
-	var resultsMutex sync.Mutex
 
+	depth := 0
 	for len(currentQueue) > 0 {
-		nextQueue := []*QueueItem{}
+		depth++
+		s.stats.reachDepth(depth)
+		s.stats.queueSize(len(currentQueue))
+
+		nextQueue := []bfsItem{}
 		var nextQueueMutex sync.Mutex
 
 		var wg sync.WaitGroup
-		currentQueueCopy := make([]*QueueItem, len(currentQueue))
+		currentQueueCopy := make([]bfsItem, len(currentQueue))
 		copy(currentQueueCopy, currentQueue)
 
 		for _, current := range currentQueueCopy {
-			wg.Add(1)
-			go func(current *QueueItem) {
-				defer wg.Done()
+			s.pool.run(&wg, func() {
+				if s.cancelled() {
+					return
+				}
 
 				processedMutex.RLock()
-				alreadyProcessed := processedElements[current.Element]
+				alreadyProcessed := processedElements[current]
 				processedMutex.RUnlock()
 
 				if alreadyProcessed {
 					return
 				}
 
-				currentNode, err := elementsModel.GetInstance().GetElementNode(current.Element)
+				currentNode, err := s.data.GetElementNode(current.Element)
 				if err != nil || currentNode == nil {
 					return
 				}
+				if !s.constraints.allowed(currentNode) {
+					processedMutex.Lock()
+					processedElements[current] = true
+					processedMutex.Unlock()
+					return
+				}
 
-				atomic.AddInt64(&NodesVisited, 1)
+				s.stats.visitNode()
 
 				if currentNode.Element.Tier == 0 || len(currentNode.Parents) == 0 {
 					tree := &TreeNode{
 						Name: currentNode.Element.Name,
 					}
 
-					treeChan <- tree
+					if s.constraints.covers(tree, current.Need) {
+						if emitted.add(s.hasher.hash(tree)) && !s.emit(tree) {
+							return
+						}
 
-					elementToTreeMutex.Lock()
-					elementToTree[currentNode.Element.Name] = []*TreeNode{tree}
-					elementToTreeMutex.Unlock()
+						elementToTreeMutex.Lock()
+						elementToTree[current] = []*TreeNode{tree}
+						elementToTreeMutex.Unlock()
+					}
 
 					processedMutex.Lock()
-					processedElements[currentNode.Element.Name] = true
+					processedElements[current] = true
 					processedMutex.Unlock()
 					return
 				}
 
 				allReady := true
-				nextItems := []*QueueItem{}
+				nextItems := []bfsItem{}
 
 				for _, recipe := range currentNode.Parents {
-					if len(recipe.SourceNodes) < 2 {
+					left, right, ok := s.bfsIngredients(currentNode, recipe, current.Need)
+					if !ok {
 						continue
 					}
 					processedMutex.RLock()
-					leftProcessed := processedElements[recipe.Recipe.Ingredients[0]]
-					rightProcessed := processedElements[recipe.Recipe.Ingredients[1]]
+					leftProcessed := processedElements[left]
+					rightProcessed := processedElements[right]
 					processedMutex.RUnlock()
 
 					if !leftProcessed {
-						nextItems = append(nextItems, &QueueItem{Element: recipe.Recipe.Ingredients[0]})
+						nextItems = append(nextItems, left)
 						allReady = false
 					}
 					if !rightProcessed {
-						nextItems = append(nextItems, &QueueItem{Element: recipe.Recipe.Ingredients[1]})
+						nextItems = append(nextItems, right)
 						allReady = false
 					}
 				}
@@ -444,15 +810,21 @@ func bfsMulti(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNod
 				}
 
 				var trees []*TreeNode
+				unique := newTreeSet()
 				for _, recipe := range currentNode.Parents {
+					left, right, ok := s.bfsIngredients(currentNode, recipe, current.Need)
+					if !ok {
+						continue
+					}
 					elementToTreeMutex.RLock()
-					leftTrees := elementToTree[recipe.Recipe.Ingredients[0]]
-					rightTrees := elementToTree[recipe.Recipe.Ingredients[1]]
+					leftTrees := elementToTree[left]
+					rightTrees := elementToTree[right]
 					elementToTreeMutex.RUnlock()
 
 					if leftTrees == nil || rightTrees == nil {
 						continue
 					}
+					s.stats.expandRecipe()
 
 					for _, left := range leftTrees {
 						for _, right := range rightTrees {
@@ -460,8 +832,13 @@ func bfsMulti(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNod
 								Name:   currentNode.Element.Name,
 								Recipe: []*TreeNode{left, right},
 							}
+							if !s.constraints.covers(node, current.Need) || !s.constraints.withinDepth(node, 1) || !unique.add(s.hasher.hash(node)) {
+								continue
+							}
 
-							treeChan <- node
+							if emitted.add(s.hasher.hash(node)) && !s.emit(node) {
+								return
+							}
 							trees = append(trees, node)
 						}
 					}
@@ -469,63 +846,53 @@ func bfsMulti(target *elementsModel.ElementNode, n int64, treeChan chan *TreeNod
 
 				if len(trees) > 0 {
 					elementToTreeMutex.Lock()
-					elementToTree[currentNode.Element.Name] = trees
+					elementToTree[current] = trees
 					elementToTreeMutex.Unlock()
-
-					processedMutex.Lock()
-					processedElements[currentNode.Element.Name] = true
-					processedMutex.Unlock()
 				}
-			}(current)
+
+				processedMutex.Lock()
+				processedElements[current] = true
+				processedMutex.Unlock()
+			})
 		}
 
 		wg.Wait()
+		if s.cancelled() {
+			return results
+		}
 		currentQueue = nextQueue
 	}
 
-	var targetWg sync.WaitGroup
+	unique := newTreeSet()
 	for _, recipe := range target.Parents {
-		targetWg.Add(1)
-		go func(recipe *elementsModel.ElementRelation) {
-			defer targetWg.Done()
-
-			elementToTreeMutex.RLock()
-			leftTrees := elementToTree[recipe.Recipe.Ingredients[0]]
-			rightTrees := elementToTree[recipe.Recipe.Ingredients[1]]
-			elementToTreeMutex.RUnlock()
-
-			if leftTrees == nil || rightTrees == nil {
-				return
-			}
-
-			localResults := []*TreeNode{}
-			for _, left := range leftTrees {
-				for _, right := range rightTrees {
-					node := &TreeNode{
-						Name:   target.Element.Name,
-						Recipe: []*TreeNode{left, right},
-					}
-					localResults = append(localResults, node)
+		left, right, ok := s.bfsIngredients(target, recipe, s.constraints.all)
+		if !ok {
+			continue
+		}
+		leftTrees := elementToTree[left]
+		rightTrees := elementToTree[right]
+		if leftTrees == nil || rightTrees == nil {
+			continue
+		}
+		s.stats.expandRecipe()
 
-					resultsMutex.Lock()
-					if len(results) >= int(n) {
-						resultsMutex.Unlock()
-						return
-					}
-					resultsMutex.Unlock()
+		for _, left := range leftTrees {
+			for _, right := range rightTrees {
+				node := &TreeNode{
+					Name:   target.Element.Name,
+					Recipe: []*TreeNode{left, right},
+				}
+				if !s.constraints.covers(node, s.constraints.all) || !s.constraints.withinDepth(node, 0) || !unique.add(s.hasher.hash(node)) {
+					continue
+				}
+				results = append(results, node)
+				if len(results) >= int(n) {
+					return results
 				}
 			}
-
-			resultsMutex.Lock()
-			results = append(results, localResults...)
-			if len(results) > int(n) {
-				results = results[:int(n)]
-			}
-			resultsMutex.Unlock()
-		}(recipe)
+		}
 	}
 
-	targetWg.Wait()
 	return results
 }
 
@@ -566,8 +933,12 @@ func PrintRecipeTree(tree *TreeNode, prefix string, isLast bool) {
 	}
 }
 
-func (ec *ElementController) GetElementByName(name string) (*elementsModel.Element, error) {
-    element, err := elementsModel.GetInstance().GetElementByName(name)
+func (ec *ElementController) GetElementByName(dataset string, name string) (*elementsModel.Element, error) {
+    data, err := elementsModel.GetRegistry().Snapshot(dataset)
+    if err != nil {
+        return nil, err
+    }
+    element, err := data.GetElementByName(name)
     if err != nil {
         return nil, fmt.Errorf("element with name '%s' not found: %v", name, err)
     }
@@ -0,0 +1,37 @@
+package elementsController
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestStartSearchRejectsUnknownMode(t *testing.T) {
+	loadTestData(t)
+
+	_, _, _, err := StartSearch(context.Background(), "bogus", false, "Mud", 1, SearchOptions{}, nil)
+	if !errors.Is(err, ErrInvalidSearch) {
+		t.Errorf("got %v, want ErrInvalidSearch", err)
+	}
+	if status := SearchStatus(err); status != "failed" {
+		t.Errorf("got status %q, want failed", status)
+	}
+
+	for _, mode := range []string{"", "dfs", "bfs", "shortest"} {
+		tree, _, _, err := StartSearch(context.Background(), mode, false, "Mud", 1, SearchOptions{}, nil)
+		if err != nil || len(tree.Recipe) != 1 {
+			t.Errorf("mode %q: got error %v", mode, err)
+		}
+	}
+}
+
+func TestWorkerPoolIsCapped(t *testing.T) {
+	for _, size := range []int{-1, 0, maxWorkers + 1, 1 << 30} {
+		if got := cap(newWorkerPool(size).slots); got != maxWorkers {
+			t.Errorf("newWorkerPool(%d): got %d slots, want %d", size, got, maxWorkers)
+		}
+	}
+	if got := cap(newWorkerPool(1).slots); got != 1 {
+		t.Errorf("newWorkerPool(1): got %d slots, want 1", got)
+	}
+}
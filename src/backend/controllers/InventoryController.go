@@ -0,0 +1,145 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"sort"
+)
+
+type Discovery struct {
+	Name    string     `json:"name"`
+	Tier    int        `json:"tier"`
+	Round   int        `json:"round"`
+	Recipes [][]string `json:"recipes"`
+}
+
+type InventoryResult struct {
+	Inventory   []string    `json:"inventory"`
+	Unknown     []string    `json:"unknown,omitempty"`
+	Discoveries []Discovery `json:"discoveries"`
+	Rounds      int         `json:"rounds"`
+	FixedPoint  bool        `json:"fixedPoint"`
+}
+
+// GetCraftable walks ElementNode.Children forward from the given inventory.
+// Round 1 holds everything craftable from the inventory alone; with closure
+// set, every discovery is added to the inventory and the walk repeats until
+// nothing new appears.
+func (ec *ElementController) GetCraftable(dataset string, inventory []string, closure bool) (*InventoryResult, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	return craftableFrom(data, inventory, closure), nil
+}
+
+func craftableFrom(data *elementsModel.Dataset, inventory []string, closure bool) *InventoryResult {
+	result := &InventoryResult{
+		Inventory:   []string{},
+		Discoveries: []Discovery{},
+	}
+
+	available := make(map[*elementsModel.ElementNode]bool)
+	var frontier []*elementsModel.ElementNode
+	for _, name := range inventory {
+		node, err := data.GetElementNode(name)
+		if err != nil {
+			result.Unknown = append(result.Unknown, name)
+			continue
+		}
+		if available[node] {
+			continue
+		}
+		available[node] = true
+		frontier = append(frontier, node)
+		result.Inventory = append(result.Inventory, node.Element.Name)
+	}
+
+	for len(frontier) > 0 {
+		round := result.Rounds + 1
+		found := make(map[*elementsModel.ElementNode]*Discovery)
+		var order []*elementsModel.ElementNode
+
+		for _, node := range frontier {
+			for _, relation := range node.Children {
+				target := relation.TargetNode
+				if available[target] || !ingredientsAvailable(relation, available) {
+					continue
+				}
+
+				discovery, exists := found[target]
+				if !exists {
+					discovery = &Discovery{
+						Name:  target.Element.Name,
+						Tier:  target.Element.Tier,
+						Round: round,
+					}
+					found[target] = discovery
+					order = append(order, target)
+				}
+				if !containsRecipe(discovery.Recipes, relation.Recipe.Ingredients) {
+					discovery.Recipes = append(discovery.Recipes, relation.Recipe.Ingredients)
+				}
+			}
+		}
+
+		if len(order) == 0 {
+			result.FixedPoint = true
+			break
+		}
+
+		result.Rounds = round
+		for _, node := range order {
+			available[node] = true
+			result.Discoveries = append(result.Discoveries, *found[node])
+		}
+		frontier = order
+
+		if !closure {
+			break
+		}
+	}
+
+	sort.SliceStable(result.Discoveries, func(i, j int) bool {
+		a, b := result.Discoveries[i], result.Discoveries[j]
+		if a.Round != b.Round {
+			return a.Round < b.Round
+		}
+		if a.Tier != b.Tier {
+			return a.Tier < b.Tier
+		}
+		return a.Name < b.Name
+	})
+
+	return result
+}
+
+func ingredientsAvailable(relation *elementsModel.ElementRelation, available map[*elementsModel.ElementNode]bool) bool {
+	if len(relation.SourceNodes) < len(relation.Recipe.Ingredients) {
+		return false
+	}
+	for _, source := range relation.SourceNodes {
+		if !available[source] {
+			return false
+		}
+	}
+	return true
+}
+
+func containsRecipe(recipes [][]string, ingredients []string) bool {
+	for _, recipe := range recipes {
+		if len(recipe) != len(ingredients) {
+			continue
+		}
+		same := true
+		for i := range recipe {
+			if recipe[i] != ingredients[i] {
+				same = false
+				break
+			}
+		}
+		if same {
+			return true
+		}
+	}
+	return false
+}
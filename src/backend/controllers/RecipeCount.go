@@ -0,0 +1,50 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"fmt"
+)
+
+type RecipeCount struct {
+	Name    string             `json:"name"`
+	Tier    int                `json:"tier"`
+	Count   string             `json:"count"`
+	Recipes []RecipeCountEntry `json:"recipes"`
+}
+
+type RecipeCountEntry struct {
+	Ingredients []string `json:"ingredients"`
+	Count       string   `json:"count"`
+}
+
+// GetRecipeCount reports the number of full recipe trees for an element using
+// the counts memoized when the graph was built. Counts are returned as decimal
+// strings because high-tier elements overflow every JSON number type.
+func (ec *ElementController) GetRecipeCount(dataset string, name string) (*RecipeCount, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	node, err := data.GetElementNode(name)
+	if err != nil {
+		return nil, fmt.Errorf("element with name '%s' not found: %v", name, err)
+	}
+
+	result := &RecipeCount{
+		Name:    node.Element.Name,
+		Tier:    node.Element.Tier,
+		Count:   node.TreeCount.String(),
+		Recipes: []RecipeCountEntry{},
+	}
+	for _, relation := range node.Parents {
+		if relation.TreeCount == nil {
+			continue
+		}
+		result.Recipes = append(result.Recipes, RecipeCountEntry{
+			Ingredients: relation.Recipe.Ingredients,
+			Count:       relation.TreeCount.String(),
+		})
+	}
+
+	return result, nil
+}
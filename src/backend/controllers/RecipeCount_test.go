@@ -0,0 +1,57 @@
+package elementsController
+
+import (
+	"context"
+	"testing"
+)
+
+// TestRecipeCountMatchesSearch checks the memoized counts against the number
+// of distinct trees DFS enumerates.
+func TestRecipeCountMatchesSearch(t *testing.T) {
+	data := loadTestData(t)
+	ec := &ElementController{}
+
+	checked := 0
+	for _, element := range data.GetAllElements() {
+		node, err := data.GetElementNode(element.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !node.TreeCount.IsInt64() || node.TreeCount.Int64() > 3000 {
+			continue
+		}
+
+		all, _, _, err := StartDFS(context.Background(), element.Name, 1<<30, SearchOptions{}, nil)
+		if err != nil {
+			t.Fatalf("%s: dfs: %v", element.Name, err)
+		}
+		want := len(all.Recipe)
+		if node.Element.Tier == 0 || len(node.Parents) == 0 {
+			want = 1
+		}
+		if got := node.TreeCount.Int64(); got != int64(want) {
+			t.Errorf("%s: counted %d trees, dfs found %d", element.Name, got, want)
+		}
+		checked++
+	}
+	if checked == 0 {
+		t.Fatal("no element was small enough to check")
+	}
+
+	count, err := ec.GetRecipeCount("", "Lake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, recipe := range count.Recipes {
+		if recipe.Ingredients[0] == "Pond" && recipe.Ingredients[1] == "Pond" {
+			found = true
+			if recipe.Count != "3" {
+				t.Errorf("Lake = Pond + Pond: got %s trees, want 3", recipe.Count)
+			}
+		}
+	}
+	if !found {
+		t.Error("Lake = Pond + Pond is not counted")
+	}
+}
@@ -0,0 +1,83 @@
+package elementsController
+
+import (
+	"strconv"
+	"strings"
+)
+
+type DAGNode struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Recipe []int  `json:"recipe,omitempty"`
+}
+
+type RecipeDAG struct {
+	Root  int       `json:"root"`
+	Nodes []DAGNode `json:"nodes"`
+}
+
+// DAGBuilder interns TreeNode subtrees so that every distinct
+// (element, recipe choice) subtree gets exactly one ID. It keeps its state
+// between calls, which lets the websocket send only the nodes a client has
+// not seen yet.
+type DAGBuilder struct {
+	byKey     map[string]int
+	byPointer map[*TreeNode]int
+	nodes     []DAGNode
+	flushed   int
+}
+
+func NewDAGBuilder() *DAGBuilder {
+	return &DAGBuilder{
+		byKey:     make(map[string]int),
+		byPointer: make(map[*TreeNode]int),
+	}
+}
+
+func (b *DAGBuilder) Add(tree *TreeNode) int {
+	if id, ok := b.byPointer[tree]; ok {
+		return id
+	}
+
+	children := make([]int, 0, len(tree.Recipe))
+	for _, child := range tree.Recipe {
+		children = append(children, b.Add(child))
+	}
+
+	var key strings.Builder
+	key.WriteString(tree.Name)
+	for _, child := range children {
+		key.WriteByte('|')
+		key.WriteString(strconv.Itoa(child))
+	}
+
+	id, ok := b.byKey[key.String()]
+	if !ok {
+		id = len(b.nodes)
+		node := DAGNode{ID: id, Name: tree.Name}
+		if len(children) > 0 {
+			node.Recipe = children
+		}
+		b.nodes = append(b.nodes, node)
+		b.byKey[key.String()] = id
+	}
+	b.byPointer[tree] = id
+	return id
+}
+
+// Flush returns the nodes added since the previous call to Flush.
+func (b *DAGBuilder) Flush() []DAGNode {
+	nodes := b.nodes[b.flushed:]
+	b.flushed = len(b.nodes)
+	return nodes
+}
+
+func BuildDAG(tree *TreeNode) *RecipeDAG {
+	if tree == nil {
+		return nil
+	}
+
+	builder := NewDAGBuilder()
+	root := builder.Add(tree)
+	return &RecipeDAG{Root: root, Nodes: builder.Flush()}
+}
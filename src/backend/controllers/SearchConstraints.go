@@ -0,0 +1,212 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"fmt"
+	"sync"
+)
+
+const maxRequiredElements = 64
+
+// searchConstraints holds the excluded and required elements and the depth
+// and tier limits of one search. Required elements are tracked as bits of a
+// mask so a branch can cheaply ask which of them it can still reach and which
+// a finished subtree contains.
+type searchConstraints struct {
+	excluded map[string]bool
+	required map[string]uint64
+	all      uint64
+	maxDepth int
+	maxTier  int
+	cycles   bool
+	reach    sync.Map
+	masks    sync.Map
+	heights  sync.Map
+}
+
+func newSearchConstraints(opts SearchOptions, data *elementsModel.Dataset) (*searchConstraints, error) {
+	if len(opts.Require) > maxRequiredElements {
+		return nil, fmt.Errorf("at most %d required elements are supported", maxRequiredElements)
+	}
+
+	if opts.MaxDepth < 0 || opts.MaxTier < 0 {
+		return nil, fmt.Errorf("depth and tier limits must not be negative")
+	}
+
+	c := &searchConstraints{
+		excluded: make(map[string]bool),
+		required: make(map[string]uint64),
+		maxDepth: opts.MaxDepth,
+		maxTier:  opts.MaxTier,
+		cycles:   opts.AllowCycles,
+	}
+	for _, name := range opts.Exclude {
+		c.excluded[name] = true
+	}
+	for _, name := range opts.Require {
+		if _, err := data.GetElementNode(name); err != nil {
+			return nil, fmt.Errorf("required element '%s' not found", name)
+		}
+		if c.excluded[name] {
+			return nil, fmt.Errorf("element '%s' is both required and excluded", name)
+		}
+		if _, exists := c.required[name]; !exists {
+			c.required[name] = 1 << uint(len(c.required))
+		}
+	}
+	for _, bit := range c.required {
+		c.all |= bit
+	}
+
+	return c, nil
+}
+
+func (c *searchConstraints) allowed(node *elementsModel.ElementNode) bool {
+	return !c.excluded[node.Element.Name]
+}
+
+func (c *searchConstraints) allowedRecipe(recipe *elementsModel.ElementRelation) bool {
+	if len(recipe.SourceNodes) < 2 {
+		return false
+	}
+	for _, source := range recipe.SourceNodes {
+		if !c.allowed(source) {
+			return false
+		}
+		if c.maxTier > 0 && source.Element.Tier > c.maxTier {
+			return false
+		}
+	}
+	return true
+}
+
+// canExpand reports whether a node at depth may still be crafted from a
+// recipe; past the depth limit only leaves are allowed.
+func (c *searchConstraints) canExpand(depth int) bool {
+	return c.maxDepth <= 0 || depth < c.maxDepth
+}
+
+func (c *searchConstraints) withinDepth(tree *TreeNode, depth int) bool {
+	return c.maxDepth <= 0 || depth+c.height(tree) <= c.maxDepth
+}
+
+func (c *searchConstraints) height(tree *TreeNode) int {
+	if cached, ok := c.heights.Load(tree); ok {
+		return cached.(int)
+	}
+
+	height := 0
+	for _, child := range tree.Recipe {
+		if childHeight := c.height(child) + 1; childHeight > height {
+			height = childHeight
+		}
+	}
+
+	c.heights.Store(tree, height)
+	return height
+}
+
+func (c *searchConstraints) bit(name string) uint64 {
+	return c.required[name]
+}
+
+// reachable returns the required elements that appear in at least one allowed
+// recipe tree of node, including node itself.
+func (c *searchConstraints) reachable(node *elementsModel.ElementNode) uint64 {
+	if c.all == 0 {
+		return 0
+	}
+	if cached, ok := c.reach.Load(node); ok {
+		return cached.(uint64)
+	}
+
+	if c.cycles {
+		mask := c.reachableWithCycles(node)
+		c.reach.Store(node, mask)
+		return mask
+	}
+
+	mask := c.bit(node.Element.Name)
+	if node.Element.Tier != 0 {
+		for _, recipe := range node.Parents {
+			if !c.allowedRecipe(recipe) {
+				continue
+			}
+			for _, source := range recipe.SourceNodes {
+				mask |= c.reachable(source)
+			}
+		}
+	}
+
+	c.reach.Store(node, mask)
+	return mask
+}
+
+// reachableWithCycles walks the recipes the tier rule drops as well. They can
+// lead back to node, so the elements below it are collected breadth first
+// instead of recursively.
+func (c *searchConstraints) reachableWithCycles(node *elementsModel.ElementNode) uint64 {
+	mask := c.bit(node.Element.Name)
+	seen := map[*elementsModel.ElementNode]bool{node: true}
+	queue := []*elementsModel.ElementNode{node}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current.Element.Tier == 0 || len(current.Parents) == 0 {
+			continue
+		}
+		recipes := append(append([]*elementsModel.ElementRelation(nil), current.Parents...), current.CyclicParents...)
+		for _, recipe := range recipes {
+			if !c.allowedRecipe(recipe) {
+				continue
+			}
+			for _, source := range recipe.SourceNodes {
+				if !seen[source] {
+					seen[source] = true
+					mask |= c.bit(source.Element.Name)
+					queue = append(queue, source)
+				}
+			}
+		}
+	}
+	return mask
+}
+
+// split decides which required elements each ingredient of recipe must
+// provide for a tree of target to cover need. Elements only one side can
+// reach are pushed down to that side; ok is false when the recipe cannot
+// cover need at all.
+func (c *searchConstraints) split(target *elementsModel.ElementNode, recipe *elementsModel.ElementRelation, need uint64) (uint64, uint64, bool) {
+	rest := need &^ c.bit(target.Element.Name)
+	if rest == 0 {
+		return 0, 0, true
+	}
+
+	leftReach := c.reachable(recipe.SourceNodes[0])
+	rightReach := c.reachable(recipe.SourceNodes[1])
+	if rest&^(leftReach|rightReach) != 0 {
+		return 0, 0, false
+	}
+	return rest &^ rightReach, rest &^ leftReach, true
+}
+
+func (c *searchConstraints) treeMask(tree *TreeNode) uint64 {
+	if c.all == 0 {
+		return 0
+	}
+	if cached, ok := c.masks.Load(tree); ok {
+		return cached.(uint64)
+	}
+
+	mask := c.bit(tree.Name)
+	for _, child := range tree.Recipe {
+		mask |= c.treeMask(child)
+	}
+
+	c.masks.Store(tree, mask)
+	return mask
+}
+
+func (c *searchConstraints) covers(tree *TreeNode, need uint64) bool {
+	return c.treeMask(tree)&need == need
+}
@@ -0,0 +1,37 @@
+package elementsController
+
+import (
+	"context"
+	"testing"
+)
+
+// TestRequiredElementsPruneSearch checks that every mode only returns trees
+// through the required element and that the BFS modes stop building the
+// intermediate trees that cannot lead to one.
+func TestRequiredElementsPruneSearch(t *testing.T) {
+	loadTestData(t)
+
+	for _, mode := range []string{"dfs", "bfs"} {
+		for _, multiThread := range []bool{false, true} {
+			plain, plainStats, _, err := StartSearch(context.Background(), mode, multiThread, "Human", 5, SearchOptions{}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			required, requiredStats, _, err := StartSearch(context.Background(), mode, multiThread, "Human", 5, SearchOptions{Require: []string{"Planet"}}, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(plain.Recipe) == 0 || len(required.Recipe) == 0 {
+				t.Fatalf("%s (multithreaded %v): got %d and %d trees", mode, multiThread, len(plain.Recipe), len(required.Recipe))
+			}
+			for _, tree := range required.Recipe {
+				if !containsElement(tree, "Planet") {
+					t.Errorf("%s (multithreaded %v): tree without Planet", mode, multiThread)
+				}
+			}
+			if mode == "bfs" && requiredStats.TreesEmitted >= plainStats.TreesEmitted {
+				t.Errorf("%s (multithreaded %v): emitted %d trees with Planet required, %d without", mode, multiThread, requiredStats.TreesEmitted, plainStats.TreesEmitted)
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package elementsController
+
+import "sync/atomic"
+
+// SearchStats is owned by a single search. Counters are updated atomically so
+// the multithreaded modes can share it between goroutines.
+type SearchStats struct {
+	NodesVisited    int64 `json:"nodesVisited"`
+	RecipesExpanded int64 `json:"recipesExpanded"`
+	TreesEmitted    int64 `json:"treesEmitted"`
+	MaxDepth        int64 `json:"maxDepth"`
+	PeakQueueSize   int64 `json:"peakQueueSize"`
+	DepthLimit      int64 `json:"depthLimit,omitempty"`
+	TierLimit       int64 `json:"tierLimit,omitempty"`
+	CyclesAvoided   int64 `json:"cyclesAvoided,omitempty"`
+}
+
+func (st *SearchStats) visitNode() {
+	atomic.AddInt64(&st.NodesVisited, 1)
+}
+
+func (st *SearchStats) expandRecipe() {
+	atomic.AddInt64(&st.RecipesExpanded, 1)
+}
+
+func (st *SearchStats) emitTree() {
+	atomic.AddInt64(&st.TreesEmitted, 1)
+}
+
+func (st *SearchStats) avoidCycle() {
+	atomic.AddInt64(&st.CyclesAvoided, 1)
+}
+
+func (st *SearchStats) reachDepth(depth int) {
+	storeMax(&st.MaxDepth, int64(depth))
+}
+
+func (st *SearchStats) queueSize(size int) {
+	storeMax(&st.PeakQueueSize, int64(size))
+}
+
+func (st *SearchStats) Snapshot() SearchStats {
+	return SearchStats{
+		NodesVisited:    atomic.LoadInt64(&st.NodesVisited),
+		RecipesExpanded: atomic.LoadInt64(&st.RecipesExpanded),
+		TreesEmitted:    atomic.LoadInt64(&st.TreesEmitted),
+		MaxDepth:        atomic.LoadInt64(&st.MaxDepth),
+		PeakQueueSize:   atomic.LoadInt64(&st.PeakQueueSize),
+		DepthLimit:      st.DepthLimit,
+		TierLimit:       st.TierLimit,
+		CyclesAvoided:   atomic.LoadInt64(&st.CyclesAvoided),
+	}
+}
+
+func storeMax(addr *int64, value int64) {
+	for {
+		current := atomic.LoadInt64(addr)
+		if value <= current || atomic.CompareAndSwapInt64(addr, current, value) {
+			return
+		}
+	}
+}
@@ -0,0 +1,463 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"container/heap"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// craftPlan is a partial tree of the shortest search with one recipe per crafted element.
+type craftPlan struct {
+	recipes map[*elementsModel.ElementNode]*elementsModel.ElementRelation
+	pending []pendingCraft
+	mask    uint64
+	bound   int
+	order   int
+}
+
+// pendingCraft is an element a plan still has to craft and the deepest
+// position it is used at.
+type pendingCraft struct {
+	node  *elementsModel.ElementNode
+	depth int
+}
+
+// craft returns the plan that crafts the first pending element with recipe,
+// or false when that recipe would use the element below itself.
+func (p *craftPlan) craft(recipe *elementsModel.ElementRelation, c *searchConstraints) (*craftPlan, bool) {
+	current := p.pending[0]
+	next := &craftPlan{
+		recipes: make(map[*elementsModel.ElementNode]*elementsModel.ElementRelation, len(p.recipes)+1),
+		pending: append([]pendingCraft(nil), p.pending[1:]...),
+		mask:    p.mask,
+	}
+	for node, chosen := range p.recipes {
+		next.recipes[node] = chosen
+	}
+	next.recipes[current.node] = recipe
+
+	for _, source := range recipe.SourceNodes {
+		next.mask |= c.bit(source.Element.Name)
+		if isLeaf(source) {
+			continue
+		}
+		if _, crafted := next.recipes[source]; crafted {
+			if next.uses(source, current.node) {
+				return nil, false
+			}
+			continue
+		}
+		next.addPending(source, current.depth+1)
+	}
+	return next, true
+}
+
+// uses reports whether the recipes chosen for node use target anywhere below
+// it, including node being target itself.
+func (p *craftPlan) uses(node *elementsModel.ElementNode, target *elementsModel.ElementNode) bool {
+	seen := make(map[*elementsModel.ElementNode]bool)
+	var walk func(node *elementsModel.ElementNode) bool
+	walk = func(node *elementsModel.ElementNode) bool {
+		if node == target {
+			return true
+		}
+		recipe, crafted := p.recipes[node]
+		if !crafted || seen[node] {
+			return false
+		}
+		seen[node] = true
+		for _, source := range recipe.SourceNodes {
+			if walk(source) {
+				return true
+			}
+		}
+		return false
+	}
+	return walk(node)
+}
+
+// addPending keeps pending ordered by tier, highest first. Without cycles
+// every element using an ingredient has a higher tier than it, so an element
+// is only crafted once all its uses are known and its depth is final.
+func (p *craftPlan) addPending(node *elementsModel.ElementNode, depth int) {
+	for i := range p.pending {
+		if p.pending[i].node == node {
+			p.pending[i].depth = max(p.pending[i].depth, depth)
+			return
+		}
+	}
+
+	pos := sort.Search(len(p.pending), func(i int) bool {
+		other := p.pending[i].node.Element
+		if other.Tier != node.Element.Tier {
+			return other.Tier < node.Element.Tier
+		}
+		return other.Name > node.Element.Name
+	})
+	p.pending = append(p.pending, pendingCraft{})
+	copy(p.pending[pos+1:], p.pending[pos:])
+	p.pending[pos] = pendingCraft{node: node, depth: depth}
+}
+
+// tree builds the recipe tree of a finished plan. Elements used in several
+// places share one subtree.
+func (p *craftPlan) tree(target *elementsModel.ElementNode) *TreeNode {
+	built := make(map[*elementsModel.ElementNode]*TreeNode)
+	var build func(node *elementsModel.ElementNode) *TreeNode
+	build = func(node *elementsModel.ElementNode) *TreeNode {
+		if tree, ok := built[node]; ok {
+			return tree
+		}
+		tree := &TreeNode{Name: node.Element.Name}
+		if recipe, crafted := p.recipes[node]; crafted {
+			tree.Recipe = []*TreeNode{build(recipe.SourceNodes[0]), build(recipe.SourceNodes[1])}
+		}
+		built[node] = tree
+		return tree
+	}
+	return build(target)
+}
+
+// planQueue pops the plan with the lowest bound first. Among equal bounds the
+// plan closest to finished wins, then the one queued first, which keeps the
+// order of results deterministic.
+type planQueue []*craftPlan
+
+func (q planQueue) Len() int { return len(q) }
+
+func (q planQueue) Less(i, j int) bool {
+	if q[i].bound != q[j].bound {
+		return q[i].bound < q[j].bound
+	}
+	if len(q[i].pending) != len(q[j].pending) {
+		return len(q[i].pending) < len(q[j].pending)
+	}
+	return q[i].order < q[j].order
+}
+
+func (q planQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *planQueue) Push(plan any) { *q = append(*q, plan.(*craftPlan)) }
+
+func (q *planQueue) Pop() any {
+	old := *q
+	plan := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return plan
+}
+
+// shortest returns up to n trees for target ordered by the number of distinct
+// crafting steps they need, fewest first. It is a best-first search over
+// craftPlans: a plan's bound never exceeds the steps of the trees it grows
+// into and never decreases as it grows, so trees come out of the queue in
+// order of their steps.
+func (s *search) shortest(target *elementsModel.ElementNode, n int64) []*TreeNode {
+	if target == nil || n <= 0 || !s.constraints.allowed(target) {
+		return nil
+	}
+	if isLeaf(target) {
+		tree := &TreeNode{Name: target.Element.Name}
+		if !s.constraints.covers(tree, s.constraints.all) || !s.emit(tree) {
+			return nil
+		}
+		return []*TreeNode{tree}
+	}
+
+	bounds := s.newStepBounds(target)
+	if !bounds.craftable(target) {
+		return nil
+	}
+
+	queue := &planQueue{}
+	first := &craftPlan{
+		recipes: make(map[*elementsModel.ElementNode]*elementsModel.ElementRelation),
+		pending: []pendingCraft{{node: target}},
+		mask:    s.constraints.bit(target.Element.Name),
+	}
+	if !s.canCover(first) || !bounds.fits(first, s.constraints) {
+		return nil
+	}
+	first.bound = bounds.of(first)
+	heap.Push(queue, first)
+	queued := 1
+
+	var results []*TreeNode
+	unique := newTreeSet()
+	for queue.Len() > 0 {
+		if s.cancelled() {
+			return results
+		}
+		plan := heap.Pop(queue).(*craftPlan)
+		s.stats.visitNode()
+
+		if len(plan.pending) == 0 {
+			tree := plan.tree(target)
+			if !s.constraints.withinDepth(tree, 0) || !unique.add(s.hasher.hash(tree)) {
+				continue
+			}
+			if !s.emit(tree) {
+				return results
+			}
+			results = append(results, tree)
+			if len(results) >= int(n) {
+				return results
+			}
+			continue
+		}
+
+		current := plan.pending[0]
+		s.stats.reachDepth(current.depth)
+		if !s.constraints.canExpand(current.depth) || queued >= maxQueuedPlans {
+			continue
+		}
+		for _, recipe := range s.planRecipes(current.node) {
+			if !bounds.usable(recipe) {
+				continue
+			}
+			s.stats.expandRecipe()
+			next, ok := plan.craft(recipe, s.constraints)
+			if !ok {
+				s.stats.avoidCycle()
+				continue
+			}
+			if !s.canCover(next) || !bounds.fits(next, s.constraints) {
+				continue
+			}
+			next.bound = bounds.of(next)
+			next.order = queued
+			queued++
+			heap.Push(queue, next)
+		}
+		s.stats.queueSize(queue.Len())
+	}
+
+	return results
+}
+
+// maxQueuedPlans caps the plans one shortest search may queue. Once it is
+// reached the plans already queued are finished but no new ones are added, so
+// a search with cycles ends even when the trees it looks for do not exist.
+const maxQueuedPlans = 1 << 16
+
+func (s *search) planRecipes(node *elementsModel.ElementNode) []*elementsModel.ElementRelation {
+	if s.constraints.cycles {
+		return s.cyclicRecipes(node)
+	}
+	return node.Parents
+}
+
+// canCover reports whether the pending elements of plan can still bring in
+// the required elements it does not contain yet.
+func (s *search) canCover(plan *craftPlan) bool {
+	missing := s.constraints.all &^ plan.mask
+	for _, pending := range plan.pending {
+		if missing == 0 {
+			break
+		}
+		missing &^= s.constraints.reachable(pending.node)
+	}
+	return missing == 0
+}
+
+// stepBounds holds the elements every tree of an element must craft and its minimum height.
+type stepBounds struct {
+	index     map[*elementsModel.ElementNode]int
+	must      [][]uint64
+	mustCount []int
+	height    []int
+	cycles    bool
+}
+
+const unreachableHeight = math.MaxInt
+
+func (s *search) newStepBounds(target *elementsModel.ElementNode) *stepBounds {
+	b := &stepBounds{
+		index:  map[*elementsModel.ElementNode]int{target: 0},
+		cycles: s.constraints.cycles,
+	}
+	nodes := []*elementsModel.ElementNode{target}
+	recipes := make([][]*elementsModel.ElementRelation, 0, 1)
+	for i := 0; i < len(nodes); i++ {
+		var allowed []*elementsModel.ElementRelation
+		if !isLeaf(nodes[i]) {
+			for _, recipe := range s.planRecipes(nodes[i]) {
+				if !s.constraints.allowedRecipe(recipe) {
+					continue
+				}
+				allowed = append(allowed, recipe)
+				for _, source := range recipe.SourceNodes {
+					if _, seen := b.index[source]; !seen {
+						b.index[source] = len(nodes)
+						nodes = append(nodes, source)
+					}
+				}
+			}
+		}
+		recipes = append(recipes, allowed)
+	}
+
+	b.height = make([]int, len(nodes))
+	for i, node := range nodes {
+		if !isLeaf(node) {
+			b.height[i] = unreachableHeight
+		}
+	}
+	for changed := true; changed; {
+		changed = false
+		for i, node := range nodes {
+			if isLeaf(node) {
+				continue
+			}
+			for _, recipe := range recipes[i] {
+				left, right := b.height[b.index[recipe.SourceNodes[0]]], b.height[b.index[recipe.SourceNodes[1]]]
+				if left == unreachableHeight || right == unreachableHeight {
+					continue
+				}
+				if height := max(left, right) + 1; height < b.height[i] {
+					b.height[i] = height
+					changed = true
+				}
+			}
+		}
+	}
+
+	words := (len(nodes) + 63) / 64
+	b.must = make([][]uint64, len(nodes))
+	for i, node := range nodes {
+		b.must[i] = make([]uint64, words)
+		if isLeaf(node) || b.height[i] == unreachableHeight {
+			continue
+		}
+		for j := range nodes {
+			b.must[i][j/64] |= 1 << uint(j%64)
+		}
+	}
+	common := make([]uint64, words)
+	for changed := true; changed; {
+		changed = false
+		for i, node := range nodes {
+			if isLeaf(node) || b.height[i] == unreachableHeight {
+				continue
+			}
+			first := true
+			for _, recipe := range recipes[i] {
+				if !b.usable(recipe) {
+					continue
+				}
+				left, right := b.must[b.index[recipe.SourceNodes[0]]], b.must[b.index[recipe.SourceNodes[1]]]
+				for w := range common {
+					if first {
+						common[w] = left[w] | right[w]
+					} else {
+						common[w] &= left[w] | right[w]
+					}
+				}
+				first = false
+			}
+			common[i/64] |= 1 << uint(i%64)
+			for w := range common {
+				if common[w] != b.must[i][w] {
+					b.must[i][w] = common[w]
+					changed = true
+				}
+			}
+		}
+	}
+
+	b.mustCount = make([]int, len(nodes))
+	for i := range nodes {
+		for _, word := range b.must[i] {
+			b.mustCount[i] += bits.OnesCount64(word)
+		}
+	}
+
+	return b
+}
+
+// craftable reports whether node has any allowed tree at all.
+func (b *stepBounds) craftable(node *elementsModel.ElementNode) bool {
+	i, ok := b.index[node]
+	return ok && b.height[i] != unreachableHeight
+}
+
+func (b *stepBounds) usable(recipe *elementsModel.ElementRelation) bool {
+	if len(recipe.SourceNodes) < 2 {
+		return false
+	}
+	for _, source := range recipe.SourceNodes {
+		if !b.craftable(source) {
+			return false
+		}
+	}
+	return true
+}
+
+// of returns a lower bound on the steps of any tree plan can grow into.
+func (b *stepBounds) of(plan *craftPlan) int {
+	steps := make([]uint64, len(b.must[0]))
+	users := make(map[*elementsModel.ElementNode][]*elementsModel.ElementNode)
+	for node, recipe := range plan.recipes {
+		i := b.index[node]
+		steps[i/64] |= 1 << uint(i%64)
+		for _, source := range recipe.SourceNodes {
+			users[source] = append(users[source], node)
+		}
+	}
+
+	bound := 0
+	tallest := 0
+	for _, pending := range plan.pending {
+		i := b.index[pending.node]
+		for w, word := range b.must[i] {
+			steps[w] |= word
+		}
+		tallest = max(tallest, b.height[i])
+		bound = max(bound, countUsers(pending.node, users)+max(b.height[i], b.mustCount[i]))
+	}
+
+	crafted := 0
+	for _, word := range steps {
+		crafted += bits.OnesCount64(word)
+	}
+	bound = max(bound, crafted)
+	if !b.cycles {
+		bound = max(bound, len(plan.recipes)+tallest)
+	}
+	return bound
+}
+
+// fits reports whether every pending element of plan can still be crafted
+// within the depth limit.
+func (b *stepBounds) fits(plan *craftPlan, c *searchConstraints) bool {
+	if c.maxDepth <= 0 {
+		return true
+	}
+	for _, pending := range plan.pending {
+		if pending.depth+b.height[b.index[pending.node]] > c.maxDepth {
+			return false
+		}
+	}
+	return true
+}
+
+// countUsers returns how many elements use node, directly or further up.
+func countUsers(node *elementsModel.ElementNode, users map[*elementsModel.ElementNode][]*elementsModel.ElementNode) int {
+	seen := make(map[*elementsModel.ElementNode]bool)
+	queue := users[node]
+	for len(queue) > 0 {
+		user := queue[0]
+		queue = queue[1:]
+		if seen[user] {
+			continue
+		}
+		seen[user] = true
+		queue = append(queue, users[user]...)
+	}
+	return len(seen)
+}
+
+func isLeaf(node *elementsModel.ElementNode) bool {
+	return node.Element.Tier == 0 || len(node.Parents) == 0
+}
@@ -0,0 +1,128 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"context"
+	"testing"
+	"time"
+)
+
+func loadTestData(t *testing.T) *elementsModel.Dataset {
+	t.Helper()
+	if err := elementsModel.GetRegistry().Load(elementsModel.DefaultDataset, "../data/elements.json", elementsModel.LoadOptions{}); err != nil {
+		t.Fatalf("loading elements: %v", err)
+	}
+	data, err := elementsModel.GetRegistry().Snapshot(elementsModel.DefaultDataset)
+	if err != nil {
+		t.Fatalf("snapshot: %v", err)
+	}
+	return data
+}
+
+func distinctSteps(tree *TreeNode) int {
+	crafted := make(map[string]bool)
+	var walk func(node *TreeNode)
+	walk = func(node *TreeNode) {
+		if len(node.Recipe) == 0 || crafted[node.Name] {
+			return
+		}
+		crafted[node.Name] = true
+		for _, child := range node.Recipe {
+			walk(child)
+		}
+	}
+	walk(tree)
+	return len(crafted)
+}
+
+// TestShortestMatchesExhaustiveSearch checks the first tree of the shortest
+// search against every tree DFS enumerates, for all elements small enough to
+// enumerate.
+func TestShortestMatchesExhaustiveSearch(t *testing.T) {
+	data := loadTestData(t)
+
+	checked := 0
+	for _, element := range data.GetAllElements() {
+		node, err := data.GetElementNode(element.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !node.TreeCount.IsInt64() || node.TreeCount.Int64() > 3000 {
+			continue
+		}
+
+		all, _, _, err := StartDFS(context.Background(), element.Name, 1<<30, SearchOptions{}, nil)
+		if err != nil {
+			t.Fatalf("%s: dfs: %v", element.Name, err)
+		}
+		if len(all.Recipe) == 0 {
+			continue
+		}
+		fewest := distinctSteps(all.Recipe[0])
+		for _, tree := range all.Recipe[1:] {
+			fewest = min(fewest, distinctSteps(tree))
+		}
+
+		shortest, _, _, err := StartShortest(context.Background(), element.Name, 5, SearchOptions{}, nil)
+		if err != nil {
+			t.Fatalf("%s: shortest: %v", element.Name, err)
+		}
+		if len(shortest.Recipe) == 0 {
+			t.Errorf("%s: shortest found no tree, want %d steps", element.Name, fewest)
+			continue
+		}
+		if got := distinctSteps(shortest.Recipe[0]); got != fewest {
+			t.Errorf("%s: shortest tree has %d steps, want %d", element.Name, got, fewest)
+		}
+		for i := 1; i < len(shortest.Recipe); i++ {
+			if distinctSteps(shortest.Recipe[i]) < distinctSteps(shortest.Recipe[i-1]) {
+				t.Errorf("%s: tree %d has fewer steps than the one before it", element.Name, i)
+			}
+		}
+		checked++
+	}
+	if checked == 0 {
+		t.Fatal("no element was small enough to check")
+	}
+}
+
+func TestShortestPlasmaReusesHeat(t *testing.T) {
+	loadTestData(t)
+
+	result, _, _, err := StartShortest(context.Background(), "Plasma", 1, SearchOptions{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Recipe) != 1 {
+		t.Fatalf("got %d trees, want 1", len(result.Recipe))
+	}
+	tree := result.Recipe[0]
+	if got := distinctSteps(tree); got != 3 {
+		t.Errorf("got %d steps, want 3", got)
+	}
+	if tree.Recipe[0].Name != "Heat" || tree.Recipe[1].Name != "Heat" {
+		t.Errorf("got Plasma = %s + %s, want Heat + Heat", tree.Recipe[0].Name, tree.Recipe[1].Name)
+	}
+}
+
+// TestShortestStopsWhenDepthIsTooShallow checks that a depth limit below the
+// height of every tree ends the search right away.
+func TestShortestStopsWhenDepthIsTooShallow(t *testing.T) {
+	loadTestData(t)
+
+	for _, depth := range []int{8, 12} {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		result, _, _, err := StartShortest(ctx, "Grilled cheese", 5, SearchOptions{MaxDepth: depth}, nil)
+		timedOut := ctx.Err() != nil
+		cancel()
+		if err != nil {
+			t.Fatalf("maxDepth %d: %v", depth, err)
+		}
+		if len(result.Recipe) != 0 {
+			t.Errorf("maxDepth %d: got %d trees, want none", depth, len(result.Recipe))
+		}
+		if timedOut {
+			t.Errorf("maxDepth %d: search ran until the timeout", depth)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package elementsController
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// treeHasher computes order-insensitive structural hashes of recipe trees, so
+// that A+B and B+A hash the same. Hashes are cached per node because searches
+// reuse the same subtree pointers in many results.
+type treeHasher struct {
+	cache sync.Map
+}
+
+func (h *treeHasher) hash(tree *TreeNode) string {
+	if cached, ok := h.cache.Load(tree); ok {
+		return cached.(string)
+	}
+
+	childHashes := make([]string, len(tree.Recipe))
+	for i, child := range tree.Recipe {
+		childHashes[i] = h.hash(child)
+	}
+	sort.Strings(childHashes)
+
+	sum := sha1.Sum([]byte(tree.Name + "(" + strings.Join(childHashes, ",") + ")"))
+	key := hex.EncodeToString(sum[:])
+	h.cache.Store(tree, key)
+	return key
+}
+
+func CanonicalHash(tree *TreeNode) string {
+	return (&treeHasher{}).hash(tree)
+}
+
+type treeSet struct {
+	mutex sync.Mutex
+	seen  map[string]bool
+}
+
+func newTreeSet() *treeSet {
+	return &treeSet{seen: make(map[string]bool)}
+}
+
+// add records key and reports whether it had not been seen before.
+func (ts *treeSet) add(key string) bool {
+	ts.mutex.Lock()
+	defer ts.mutex.Unlock()
+
+	if ts.seen[key] {
+		return false
+	}
+	ts.seen[key] = true
+	return true
+}
@@ -0,0 +1,113 @@
+package elementsController
+
+import (
+	elementsModel "backend/models"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+type ElementUse struct {
+	Element     string   `json:"element"`
+	Tier        int      `json:"tier"`
+	Ingredients []string `json:"ingredients"`
+	Dropped     bool     `json:"dropped,omitempty"`
+	Reason      string   `json:"reason,omitempty"`
+}
+
+type ElementUses struct {
+	Name  string                  `json:"name"`
+	Tier  int                     `json:"tier"`
+	Total int                     `json:"total"`
+	Tiers map[string][]ElementUse `json:"tiers"`
+}
+
+type UsesOptions struct {
+	Tier           *int
+	IncludeDropped bool
+}
+
+// GetElementUses lists the recipes that take name as an ingredient, grouped
+// by the tier of the element they make. Recipes kept in the graph come from
+// ElementNode.Children; with IncludeDropped the recipes buildElementGraph left
+// out are added along with the reason they were dropped.
+func (ec *ElementController) GetElementUses(dataset string, name string, opts UsesOptions) (*ElementUses, error) {
+	data, err := elementsModel.GetRegistry().Snapshot(dataset)
+	if err != nil {
+		return nil, err
+	}
+	node, err := data.GetElementNode(name)
+	if err != nil {
+		return nil, fmt.Errorf("element with name '%s' not found: %v", name, err)
+	}
+
+	result := &ElementUses{
+		Name:  node.Element.Name,
+		Tier:  node.Element.Tier,
+		Tiers: make(map[string][]ElementUse),
+	}
+	add := func(use ElementUse) {
+		if opts.Tier != nil && use.Tier != *opts.Tier {
+			return
+		}
+		tierStr := strconv.Itoa(use.Tier)
+		result.Tiers[tierStr] = append(result.Tiers[tierStr], use)
+		result.Total++
+	}
+
+	kept := make(map[string]bool)
+	for _, relation := range node.Children {
+		if len(relation.SourceNodes) != len(relation.Recipe.Ingredients) {
+			continue
+		}
+		target := relation.TargetNode
+		key := fmt.Sprint(target.Element.Name, relation.Recipe.Ingredients)
+		if kept[key] {
+			continue
+		}
+		kept[key] = true
+
+		add(ElementUse{
+			Element:     target.Element.Name,
+			Tier:        target.Element.Tier,
+			Ingredients: relation.Recipe.Ingredients,
+		})
+	}
+
+	if opts.IncludeDropped {
+		graph := data.GetElementGraph()
+		for _, dropped := range graph.Dropped {
+			if !containsIngredient(dropped.Ingredients, node.Element.Name) {
+				continue
+			}
+
+			add(ElementUse{
+				Element:     dropped.Element,
+				Tier:        dropped.Tier,
+				Ingredients: dropped.Ingredients,
+				Dropped:     true,
+				Reason:      string(dropped.Reason),
+			})
+		}
+	}
+
+	for _, uses := range result.Tiers {
+		sort.SliceStable(uses, func(i, j int) bool {
+			if uses[i].Element != uses[j].Element {
+				return uses[i].Element < uses[j].Element
+			}
+			return !uses[i].Dropped && uses[j].Dropped
+		})
+	}
+
+	return result, nil
+}
+
+func containsIngredient(ingredients []string, name string) bool {
+	for _, ingredient := range ingredients {
+		if ingredient == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package elementsController
+
+import (
+	"runtime"
+	"sync"
+)
+
+// workerPool bounds the number of goroutines a multithreaded search may start.
+// When every slot is taken the job runs on the calling goroutine instead, so
+// recursive submissions from inside a job can never deadlock the pool.
+type workerPool struct {
+	slots chan struct{}
+}
+
+// maxWorkers caps the pool size a client may ask for. The searches are CPU
+// bound, so goroutines beyond the number of CPUs only add overhead.
+var maxWorkers = runtime.NumCPU()
+
+func newWorkerPool(size int) *workerPool {
+	if size <= 0 || size > maxWorkers {
+		size = maxWorkers
+	}
+	return &workerPool{slots: make(chan struct{}, size)}
+}
+
+func (p *workerPool) run(wg *sync.WaitGroup, job func()) {
+	wg.Add(1)
+	select {
+	case p.slots <- struct{}{}:
+		go func() {
+			defer func() {
+				<-p.slots
+				wg.Done()
+			}()
+			job()
+		}()
+	default:
+		defer wg.Done()
+		job()
+	}
+}
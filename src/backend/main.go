@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"time"
 
 	elementsModel "backend/models"
 	"backend/routes"
@@ -20,16 +23,242 @@ func main() {
 	filepath:=filepath.Join(cwd, "src","backend","data", "elements.json") // for docker
 	// filepath:=filepath.Join(cwd, "data", "elements.json")
 
-	log.Println("Scraping data...")
-	scraper.Scrape(filepath)
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(validate(os.Args[2:], filepath))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "scrape" {
+		os.Exit(scrape(os.Args[2:]))
+	}
+
+	iconDir := iconCacheDir(filepath)
+
+	source := scraper.WikiURL
+	if snapshot := os.Getenv("SCRAPE_SOURCE"); snapshot != "" {
+		source = snapshot
+	}
+	log.Printf("Scraping data from %s...", source)
+	previous, previousErr := elementsModel.ReadElements(filepath)
+	elements, scrapeReport, err := scraper.Scrape(source)
+	if err == nil {
+		log.Printf("Scraped %d elements with %d recipes, %d problems", scrapeReport.Elements, scrapeReport.Recipes, scrapeReport.Problems())
+		scrapeDetails(elements, &scrapeReport, filepath, iconDir, true)
+		err = scraper.Save(elements, filepath)
+	}
+	refreshDetailsLater := err == nil && scraper.IsURL(scrapeReport.Source)
+	if err == nil && previousErr == nil {
+		recordChangelog(previous, filepath)
+	}
+	if err != nil {
+		if _, statErr := os.Stat(filepath); statErr != nil {
+			log.Fatalf("Scraping failed and there is no existing %s: %v", filepath, err)
+		}
+		log.Printf("Scraping failed, using the existing %s: %v", filepath, err)
+	}
+
+	loadOptions := elementsModel.LoadOptions{IconDir: iconDir}
+	if mode := os.Getenv("RECOMPUTE_TIERS"); mode != "" {
+		tierMode, err := elementsModel.ParseTierMode(mode)
+		if err != nil {
+			log.Fatalf("invalid RECOMPUTE_TIERS: %v", err)
+		}
+		loadOptions.RecomputeTiers = tierMode
+	}
+
+	datasets, err := elementsModel.ParseDatasets(os.Getenv("DATASETS"))
+	if err != nil {
+		log.Fatalf("invalid DATASETS: %v", err)
+	}
+	datasets[elementsModel.DefaultDataset] = filepath
 
 	log.Println("Initializing elements model...")
-	errr := elementsModel.GetInstance().Initialize(filepath)
-	if errr != nil {
-		log.Fatalf("error initializing elements service: %v", errr)
+	registry := elementsModel.GetRegistry()
+	for name, path := range datasets {
+		if err := registry.Load(name, path, loadOptions); err != nil {
+			log.Fatalf("error initializing elements service: %v", err)
+		}
+	}
+
+	for _, name := range registry.Names() {
+		data, _ := registry.Snapshot(name)
+
+		validation := data.GetValidationReport()
+		log.Printf("[%s] Validated elements: %d errors, %d warnings", name, validation.Errors, validation.Warnings)
+
+		report := data.GetIntegrityReport()
+		log.Printf("[%s] Kept %d of %d recipes, dropped %d (%d higher tier ingredient, %d self reference, %d missing element)",
+			name, report.KeptRecipes, report.TotalRecipes, len(report.Dropped),
+			report.ByReason[elementsModel.DropHigherTier],
+			report.ByReason[elementsModel.DropSelfReference],
+			report.ByReason[elementsModel.DropMissingElement])
+	}
+
+	if interval := os.Getenv("WATCH_ELEMENTS"); interval != "" {
+		pollInterval, err := time.ParseDuration(interval)
+		if err != nil || pollInterval <= 0 {
+			log.Fatalf("invalid WATCH_ELEMENTS: must be a positive duration such as 5s")
+		}
+		for _, name := range registry.Names() {
+			service, _ := registry.Get(name)
+			log.Printf("Watching %s (%s) for changes every %s", datasets[name], name, pollInterval)
+			go service.Watch(context.Background(), pollInterval, logReload(name))
+		}
+	}
+
+	if refreshDetailsLater {
+		go refreshDetails(elements, filepath, iconDir)
 	}
 
 	log.Println("Starting server on http://0.0.0.0:4003")
 	router := routes.InitRoutes()
 	log.Fatal(http.ListenAndServe(":4003", router))
-}
\ No newline at end of file
+}
+
+func logReload(dataset string) func(*elementsModel.ReloadDiff, error) {
+	return func(diff *elementsModel.ReloadDiff, err error) {
+		if err != nil {
+			log.Printf("Reloading dataset %s failed, keeping the current data: %v", dataset, err)
+			return
+		}
+		log.Printf("Reloaded dataset %s: %s", dataset, diff)
+	}
+}
+
+// validate checks an elements file, printing the report as JSON. It returns
+// the exit code: 1 when the data has errors and 2 when it cannot be read.
+func validate(args []string, defaultPath string) int {
+	path := defaultPath
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	report, err := elementsModel.ValidateFile(path)
+	if err != nil {
+		log.Printf("cannot validate %s: %v", path, err)
+		return 2
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	log.Printf("%s: %d errors, %d warnings", path, report.Errors, report.Warnings)
+	if report.HasErrors() {
+		return 1
+	}
+	return 0
+}
+
+// iconCacheDir is ICON_CACHE_DIR when set and otherwise the icons directory
+// next to the data file.
+func iconCacheDir(dataPath string) string {
+	if dir := os.Getenv("ICON_CACHE_DIR"); dir != "" {
+		return dir
+	}
+	return elementsModel.DefaultIconDir(dataPath)
+}
+
+// scrapeDetails adds links, icons and descriptions to freshly scraped
+// elements, reusing the details already saved in the data file at dataPath.
+// Offline it never follows pages to the wiki and only picks up icons that are
+// already cached.
+func scrapeDetails(elements []scraper.Element, report *scraper.ScrapeReport, dataPath string, iconDir string, offline bool) {
+	previous, _ := scraper.Load(dataPath)
+	details := scraper.ScrapeDetails(elements, scraper.DetailOptions{
+		IconDir:  iconDir,
+		Previous: previous,
+		Offline:  offline,
+	})
+	report.Details = &details
+
+	log.Printf("Element details: %d pages visited, %d icons downloaded, %d icons cached, %d failed",
+		details.PagesVisited, details.IconsDownloaded, details.IconsCached, len(details.Failed))
+}
+
+// refreshDetails visits the wiki pages and downloads the icons startup left
+// out, then saves them and reloads the default dataset. It runs once the
+// server is up, so a cold icon cache does not hold up startup.
+func refreshDetails(elements []scraper.Element, dataPath string, iconDir string) {
+	elements = append([]scraper.Element(nil), elements...)
+	details := scraper.ScrapeDetails(elements, scraper.DetailOptions{
+		IconDir:  iconDir,
+		Previous: elements,
+	})
+	log.Printf("Element details: %d pages visited, %d icons downloaded, %d icons cached, %d failed",
+		details.PagesVisited, details.IconsDownloaded, details.IconsCached, len(details.Failed))
+	if details.PagesVisited == 0 && details.IconsDownloaded == 0 {
+		return
+	}
+
+	if err := scraper.Save(elements, dataPath); err != nil {
+		log.Printf("Cannot save element details to %s: %v", dataPath, err)
+		return
+	}
+	service, err := elementsModel.GetRegistry().Get(elementsModel.DefaultDataset)
+	if err != nil {
+		log.Printf("Cannot reload element details: %v", err)
+		return
+	}
+	logReload(elementsModel.DefaultDataset)(service.Reload())
+}
+
+// recordChangelog compares the freshly saved data file with the elements it
+// replaced and stores a changelog when anything changed.
+func recordChangelog(previous []elementsModel.Element, path string) {
+	current, err := elementsModel.ReadElements(path)
+	if err != nil {
+		log.Printf("Cannot read %s for the changelog: %v", path, err)
+		return
+	}
+
+	changelog := elementsModel.CompareElements(previous, current)
+	if changelog.Empty() {
+		log.Println("Scraped data is unchanged")
+		return
+	}
+
+	saved, err := elementsModel.SaveChangelog(path, changelog)
+	if err != nil {
+		log.Printf("Cannot save changelog: %v", err)
+		return
+	}
+	log.Printf("Scraped data changed (%s), changelog saved to %s", changelog, saved)
+}
+
+// scrape runs the scraper without starting the server and prints its report
+// as JSON, saving the elements when an output path is given. It returns the
+// exit code: 1 when the report lists problems and 2 when scraping failed.
+func scrape(args []string) int {
+	source := scraper.WikiURL
+	if len(args) > 0 {
+		source = args[0]
+	}
+
+	elements, report, err := scraper.Scrape(source)
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(report)
+
+	if err != nil {
+		log.Printf("cannot scrape %s: %v", source, err)
+		return 2
+	}
+	if len(args) > 1 {
+		previous, previousErr := elementsModel.ReadElements(args[1])
+		scrapeDetails(elements, &report, args[1], iconCacheDir(args[1]), !scraper.IsURL(report.Source))
+		if err := scraper.Save(elements, args[1]); err != nil {
+			log.Printf("cannot save %s: %v", args[1], err)
+			return 2
+		}
+		log.Println("Data successfully saved to", args[1])
+		if previousErr == nil {
+			recordChangelog(previous, args[1])
+		}
+	}
+
+	log.Printf("%s: %d elements, %d problems", source, report.Elements, report.Problems())
+	if report.Problems() > 0 {
+		return 1
+	}
+	return 0
+}
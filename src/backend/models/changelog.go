@@ -0,0 +1,137 @@
+package elementsModel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const changelogTimeFormat = "20060102T150405Z"
+
+var ErrNoChangelog = errors.New("no changelog recorded")
+
+type TierChange struct {
+	Element string `json:"element"`
+	From    int    `json:"from"`
+	To      int    `json:"to"`
+}
+
+// Changelog records how a scrape changed the element data compared with the
+// data file it replaced.
+type Changelog struct {
+	GeneratedAt     time.Time      `json:"generatedAt"`
+	Previous        int            `json:"previous"`
+	Current         int            `json:"current"`
+	AddedElements   []string       `json:"addedElements"`
+	RemovedElements []string       `json:"removedElements"`
+	TierChanges     []TierChange   `json:"tierChanges"`
+	AddedRecipes    []RecipeChange `json:"addedRecipes"`
+	RemovedRecipes  []RecipeChange `json:"removedRecipes"`
+}
+
+func (c *Changelog) Empty() bool {
+	return len(c.AddedElements) == 0 && len(c.RemovedElements) == 0 && len(c.TierChanges) == 0 &&
+		len(c.AddedRecipes) == 0 && len(c.RemovedRecipes) == 0
+}
+
+func (c *Changelog) String() string {
+	return fmt.Sprintf("elements +%d/-%d, %d tier changes, recipes +%d/-%d",
+		len(c.AddedElements), len(c.RemovedElements), len(c.TierChanges), len(c.AddedRecipes), len(c.RemovedRecipes))
+}
+
+func CompareElements(previous []Element, current []Element) *Changelog {
+	changelog := &Changelog{
+		GeneratedAt:     time.Now().UTC(),
+		Previous:        len(previous),
+		Current:         len(current),
+		AddedElements:   elementsMissingFrom(current, previous),
+		RemovedElements: elementsMissingFrom(previous, current),
+		TierChanges:     []TierChange{},
+		AddedRecipes:    recipesMissingFrom(current, previous),
+		RemovedRecipes:  recipesMissingFrom(previous, current),
+	}
+
+	tiers := make(map[string]int)
+	for _, element := range previous {
+		tiers[element.Name] = element.Tier
+	}
+	changed := make(map[string]bool)
+	for _, element := range current {
+		tier, exists := tiers[element.Name]
+		if !exists || tier == element.Tier || changed[element.Name] {
+			continue
+		}
+		changed[element.Name] = true
+		changelog.TierChanges = append(changelog.TierChanges, TierChange{
+			Element: element.Name,
+			From:    tier,
+			To:      element.Tier,
+		})
+	}
+	sort.Slice(changelog.TierChanges, func(i, j int) bool {
+		return changelog.TierChanges[i].Element < changelog.TierChanges[j].Element
+	})
+
+	return changelog
+}
+
+// ChangelogDir is where the changelogs of a data file are kept, e.g.
+// data/changelog for data/elements.json. Each one is named after the data
+// file and the time it was generated, so the latest sorts last.
+func ChangelogDir(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), "changelog")
+}
+
+func changelogPrefix(filePath string) string {
+	return strings.TrimSuffix(filepath.Base(filePath), filepath.Ext(filePath)) + "-"
+}
+
+func SaveChangelog(filePath string, changelog *Changelog) (string, error) {
+	dir := ChangelogDir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	data, err := json.MarshalIndent(changelog, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, changelogPrefix(filePath)+changelog.GeneratedAt.UTC().Format(changelogTimeFormat)+".json")
+	return path, os.WriteFile(path, data, 0644)
+}
+
+func LatestChangelog(filePath string) (*Changelog, error) {
+	paths, err := filepath.Glob(filepath.Join(ChangelogDir(filePath), changelogPrefix(filePath)+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, ErrNoChangelog
+	}
+	sort.Strings(paths)
+
+	data, err := os.ReadFile(paths[len(paths)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	changelog := &Changelog{}
+	if err := json.Unmarshal(data, changelog); err != nil {
+		return nil, err
+	}
+	return changelog, nil
+}
+
+func (es *ElementsService) GetChangelog() (*Changelog, error) {
+	es.mutex.RLock()
+	filePath := es.filePath
+	es.mutex.RUnlock()
+
+	return LatestChangelog(filePath)
+}
@@ -0,0 +1,60 @@
+package elementsModel
+
+import "math/big"
+
+// countRecipeTrees returns how many distinct full recipe trees produce node,
+// filling in TreeCount on the node and on each of its Parents relations.
+// Parents only point to strictly lower tiers, so the recursion terminates and
+// every node is counted once. Trees are counted the way the searches tell
+// them apart, regardless of ingredient order: a recipe listed twice is only
+// counted once, leaving TreeCount nil on the repeat, and a recipe using the
+// same ingredient twice counts each pair of its trees once.
+func countRecipeTrees(node *ElementNode) *big.Int {
+	if node.TreeCount != nil {
+		return node.TreeCount
+	}
+
+	if len(node.Parents) == 0 || node.Element.Tier == 0 {
+		node.TreeCount = big.NewInt(1)
+		return node.TreeCount
+	}
+
+	total := new(big.Int)
+	counted := make(map[string]bool)
+	for _, relation := range node.Parents {
+		relation.TreeCount = nil
+		if len(relation.SourceNodes) < 2 {
+			relation.TreeCount = new(big.Int)
+			continue
+		}
+		key := recipeKey(node.Element.Name, relation.Recipe)
+		if counted[key] {
+			continue
+		}
+		counted[key] = true
+
+		relation.TreeCount = countRecipe(relation.SourceNodes)
+		total.Add(total, relation.TreeCount)
+	}
+
+	node.TreeCount = total
+	return total
+}
+
+// countRecipe multiplies the tree counts of the ingredients. When both
+// ingredients are the same element with k trees, swapping the two subtrees
+// gives the same tree, so only the k(k+1)/2 unordered pairs are distinct.
+func countRecipe(sources []*ElementNode) *big.Int {
+	left := countRecipeTrees(sources[0])
+	if len(sources) == 2 && sources[0] == sources[1] {
+		count := new(big.Int).Add(left, big.NewInt(1))
+		count.Mul(count, left)
+		return count.Rsh(count, 1)
+	}
+
+	count := new(big.Int).Set(left)
+	for _, source := range sources[1:] {
+		count.Mul(count, countRecipeTrees(source))
+	}
+	return count
+}
@@ -3,20 +3,24 @@ package elementsModel
 import (
 	"encoding/json"
 	"errors"
+	"math/big"
 	"os"
 	"sync"
 )
 
 type ElementNode struct {
-	Element  *Element
-	Children []*ElementRelation
-	Parents  []*ElementRelation
+	Element       *Element
+	Children      []*ElementRelation
+	Parents       []*ElementRelation
+	CyclicParents []*ElementRelation
+	TreeCount     *big.Int
 }
 
 type ElementRelation struct {
 	TargetNode  *ElementNode
 	SourceNodes []*ElementNode
 	Recipe      Recipe
+	TreeCount   *big.Int
 }
 
 type Recipe struct {
@@ -24,41 +28,59 @@ type Recipe struct {
 }
 
 type Element struct {
-	Name    string   `json:"name"`
-	Tier    int      `json:"tier"`
-	Recipes []Recipe `json:"recipes"`
+	Name        string   `json:"name"`
+	Tier        int      `json:"tier"`
+	Recipes     []Recipe `json:"recipes"`
+	Link        string   `json:"link,omitempty"`
+	IconURL     string   `json:"iconUrl,omitempty"`
+	Icon        string   `json:"icon,omitempty"`
+	Description string   `json:"description,omitempty"`
 }
 
 type ElementGraph struct {
 	RootNode   *ElementNode
 	AllNodes   map[string]*ElementNode
 	Tier0Nodes []*ElementNode
+	Dropped    []*DroppedRecipe
+}
+
+// Dataset is one load of the element data. It is never modified once built,
+// so a search can keep using the Dataset it started with while a reload swaps
+// in a new one.
+type Dataset struct {
+	elements      []Element
+	elementsMap   map[string]*Element
+	graph         *ElementGraph
+	validation    *ValidationReport
+	declaredTiers map[string]int
 }
 
 type ElementsService struct {
-	elements    []Element
-	elementsMap map[string]*Element
-	graph       *ElementGraph
+	data        *Dataset
 	filePath    string
+	options     LoadOptions
 	initialized bool
 	mutex       sync.RWMutex
+	reloadMutex sync.Mutex
 }
 
-var (
-	instance *ElementsService
-	once     sync.Once
-)
+// LoadOptions changes how the element data is turned into a graph. An empty
+// RecomputeTiers keeps the tiers declared in the data file, and an empty
+// IconDir looks for icons in DefaultIconDir.
+type LoadOptions struct {
+	RecomputeTiers TierMode
+	IconDir        string
+}
 
-func GetInstance() *ElementsService {
-	once.Do(func() {
-		instance = &ElementsService{
-			elementsMap: make(map[string]*Element),
-		}
-	})
-	return instance
+func NewElementsService() *ElementsService {
+	return &ElementsService{}
 }
 
 func (es *ElementsService) Initialize(filePath string) error {
+	return es.InitializeWith(filePath, LoadOptions{})
+}
+
+func (es *ElementsService) InitializeWith(filePath string, opts LoadOptions) error {
 	es.mutex.Lock()
 	defer es.mutex.Unlock()
 
@@ -66,37 +88,89 @@ func (es *ElementsService) Initialize(filePath string) error {
 		return nil
 	}
 
-	file, err := os.Open(filePath)
+	data, err := loadDataset(filePath, opts)
 	if err != nil {
 		return err
 	}
+
+	es.data = data
+	es.filePath = filePath
+	es.options = opts
+
+	es.initialized = true
+	return nil
+}
+
+// Snapshot returns the dataset currently in use.
+func (es *ElementsService) Snapshot() *Dataset {
+	es.mutex.RLock()
+	defer es.mutex.RUnlock()
+	return es.data
+}
+
+// loadDataset reads the data file and applies its overlay of custom elements
+// on top before building the graph.
+func loadDataset(filePath string, opts LoadOptions) (*Dataset, error) {
+	elements, err := ReadElements(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	overlay, err := readOverlay(OverlayPath(filePath))
+	if err != nil {
+		return nil, err
+	}
+
+	return newDataset(overlay.apply(elements), opts), nil
+}
+
+func ReadElements(filePath string) ([]Element, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
 	defer file.Close()
 
 	data, err := os.ReadFile(filePath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	var elements []Element
 	err = json.Unmarshal(data, &elements)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	return elements, nil
+}
+
+func newDataset(elements []Element, opts LoadOptions) *Dataset {
+	dataset := &Dataset{
+		elementsMap:   make(map[string]*Element),
+		validation:    ValidateElements(elements),
+		declaredTiers: make(map[string]int, len(elements)),
+	}
+
+	for _, element := range elements {
+		dataset.declaredTiers[element.Name] = element.Tier
+	}
+	if opts.RecomputeTiers != "" {
+		recomputeTiers(elements, opts.RecomputeTiers)
 	}
 
 	for i := range elements {
-		es.elementsMap[elements[i].Name] = &elements[i]
+		dataset.elementsMap[elements[i].Name] = &elements[i]
 	}
 
-	es.elements = elements
-	es.filePath = filePath
+	dataset.elements = elements
 
-	es.buildElementGraph()
+	dataset.buildElementGraph()
 
-	es.initialized = true
-	return nil
+	return dataset
 }
 
-func (es *ElementsService) buildElementGraph() {
+func (d *Dataset) buildElementGraph() {
 	graph := &ElementGraph{
 		RootNode: &ElementNode{
 			Element:  nil,
@@ -107,7 +181,7 @@ func (es *ElementsService) buildElementGraph() {
 		Tier0Nodes: []*ElementNode{},
 	}
 
-	for name, element := range es.elementsMap {
+	for name, element := range d.elementsMap {
 		node := &ElementNode{
 			Element:  element,
 			Children: []*ElementRelation{},
@@ -132,6 +206,13 @@ func (es *ElementsService) buildElementGraph() {
 				Recipe:      recipe,
 			}
 
+			if dropped, ok := dropReason(graph, node, recipe); ok {
+				graph.Dropped = append(graph.Dropped, dropped)
+				if dropped.Reason != DropMissingElement {
+					node.CyclicParents = append(node.CyclicParents, cyclicRelation(graph, node, recipe))
+				}
+			}
+
 			shouldAddParent := true
 			for _, ingredientName := range recipe.Ingredients {
 				if ingredientNode, exists := graph.AllNodes[ingredientName]; exists {
@@ -150,6 +231,8 @@ func (es *ElementsService) buildElementGraph() {
 		}
 	}
 
+	sortDroppedRecipes(graph.Dropped)
+
 	for _, tier0Node := range graph.Tier0Nodes {
 		rootRelation := &ElementRelation{
 			TargetNode:  tier0Node,
@@ -159,38 +242,47 @@ func (es *ElementsService) buildElementGraph() {
 		graph.RootNode.Children = append(graph.RootNode.Children, rootRelation)
 	}
 
-	es.graph = graph
+	for _, node := range graph.AllNodes {
+		countRecipeTrees(node)
+	}
+
+	d.graph = graph
 }
 
 func (es *ElementsService) GetElementGraph() *ElementGraph {
-	es.mutex.RLock()
-	defer es.mutex.RUnlock()
-	return es.graph
+	return es.Snapshot().GetElementGraph()
 }
 
 func (es *ElementsService) GetAllElements() []Element {
-	es.mutex.RLock()
-	defer es.mutex.RUnlock()
-
-	return es.elements
+	return es.Snapshot().GetAllElements()
 }
 
 func (es *ElementsService) GetElementByName(name string) (*Element, error) {
-	es.mutex.RLock()
-	defer es.mutex.RUnlock()
+	return es.Snapshot().GetElementByName(name)
+}
+
+func (es *ElementsService) GetElementNode(name string) (*ElementNode, error) {
+	return es.Snapshot().GetElementNode(name)
+}
 
-	if element, exists := es.elementsMap[name]; exists {
+func (d *Dataset) GetElementGraph() *ElementGraph {
+	return d.graph
+}
+
+func (d *Dataset) GetAllElements() []Element {
+	return d.elements
+}
+
+func (d *Dataset) GetElementByName(name string) (*Element, error) {
+	if element, exists := d.elementsMap[name]; exists {
 		return element, nil
 	}
 
 	return nil, errors.New("element not found")
 }
 
-func (es *ElementsService) GetElementNode(name string) (*ElementNode, error) {
-	es.mutex.RLock()
-	defer es.mutex.RUnlock()
-
-	if node, exists := es.graph.AllNodes[name]; exists {
+func (d *Dataset) GetElementNode(name string) (*ElementNode, error) {
+	if node, exists := d.graph.AllNodes[name]; exists {
 		return node, nil
 	}
 
@@ -0,0 +1,40 @@
+package elementsModel
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrNoIcon = errors.New("element has no cached icon")
+
+// DefaultIconDir is where icons are cached when no directory is configured,
+// e.g. data/icons for data/elements.json.
+func DefaultIconDir(filePath string) string {
+	return filepath.Join(filepath.Dir(filePath), "icons")
+}
+
+// IconPath returns the cached icon file of the named element.
+func (es *ElementsService) IconPath(name string) (string, error) {
+	es.mutex.RLock()
+	data, iconDir := es.data, es.options.IconDir
+	if iconDir == "" {
+		iconDir = DefaultIconDir(es.filePath)
+	}
+	es.mutex.RUnlock()
+
+	element, err := data.GetElementByName(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: '%s'", ErrElementNotFound, name)
+	}
+	if element.Icon == "" {
+		return "", fmt.Errorf("%w: '%s'", ErrNoIcon, name)
+	}
+
+	path := filepath.Join(iconDir, filepath.Base(element.Icon))
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("%w: '%s'", ErrNoIcon, name)
+	}
+	return path, nil
+}
@@ -0,0 +1,108 @@
+package elementsModel
+
+import "sort"
+
+type DropReason string
+
+const (
+	DropHigherTier     DropReason = "higher tier ingredient"
+	DropSelfReference  DropReason = "self reference"
+	DropMissingElement DropReason = "missing element"
+)
+
+// DroppedRecipe is a recipe from the element data that buildElementGraph did
+// not keep as a usable relation. Ingredient is the first ingredient that
+// caused it to be dropped.
+type DroppedRecipe struct {
+	Element     string     `json:"element"`
+	Tier        int        `json:"tier"`
+	Ingredients []string   `json:"ingredients"`
+	Ingredient  string     `json:"ingredient"`
+	Reason      DropReason `json:"reason"`
+}
+
+type IntegrityReport struct {
+	TotalRecipes int                `json:"totalRecipes"`
+	KeptRecipes  int                `json:"keptRecipes"`
+	ByReason     map[DropReason]int `json:"byReason"`
+	Dropped      []*DroppedRecipe   `json:"dropped"`
+}
+
+// dropReason checks a recipe of node against the tier rule used by
+// buildElementGraph and returns why it cannot be used, if at all. Missing
+// ingredients are reported first since nothing can make such a recipe usable.
+func dropReason(graph *ElementGraph, node *ElementNode, recipe Recipe) (*DroppedRecipe, bool) {
+	dropped := &DroppedRecipe{
+		Element:     node.Element.Name,
+		Tier:        node.Element.Tier,
+		Ingredients: recipe.Ingredients,
+	}
+
+	for _, ingredientName := range recipe.Ingredients {
+		if _, exists := graph.AllNodes[ingredientName]; !exists {
+			dropped.Ingredient = ingredientName
+			dropped.Reason = DropMissingElement
+			return dropped, true
+		}
+	}
+
+	for _, ingredientName := range recipe.Ingredients {
+		ingredientNode := graph.AllNodes[ingredientName]
+		switch {
+		case ingredientNode == node:
+			dropped.Reason = DropSelfReference
+		case ingredientNode.Element.Tier >= node.Element.Tier:
+			dropped.Reason = DropHigherTier
+		default:
+			continue
+		}
+		dropped.Ingredient = ingredientName
+		return dropped, true
+	}
+
+	return nil, false
+}
+
+// cyclicRelation keeps a recipe dropped by the tier rule, with every
+// ingredient as a source, so searches that detect cycles themselves can still
+// use it. It is not linked into the ingredients' Children.
+func cyclicRelation(graph *ElementGraph, node *ElementNode, recipe Recipe) *ElementRelation {
+	relation := &ElementRelation{
+		TargetNode:  node,
+		SourceNodes: []*ElementNode{},
+		Recipe:      recipe,
+	}
+	for _, ingredientName := range recipe.Ingredients {
+		relation.SourceNodes = append(relation.SourceNodes, graph.AllNodes[ingredientName])
+	}
+	return relation
+}
+
+func sortDroppedRecipes(dropped []*DroppedRecipe) {
+	sort.SliceStable(dropped, func(i, j int) bool {
+		if dropped[i].Tier != dropped[j].Tier {
+			return dropped[i].Tier < dropped[j].Tier
+		}
+		return dropped[i].Element < dropped[j].Element
+	})
+}
+
+func (es *ElementsService) GetIntegrityReport() *IntegrityReport {
+	return es.Snapshot().GetIntegrityReport()
+}
+
+func (d *Dataset) GetIntegrityReport() *IntegrityReport {
+	report := &IntegrityReport{
+		ByReason: make(map[DropReason]int),
+		Dropped:  d.graph.Dropped,
+	}
+	for _, element := range d.elements {
+		report.TotalRecipes += len(element.Recipes)
+	}
+	for _, dropped := range d.graph.Dropped {
+		report.ByReason[dropped.Reason]++
+	}
+	report.KeptRecipes = report.TotalRecipes - len(d.graph.Dropped)
+
+	return report
+}
@@ -0,0 +1,259 @@
+package elementsModel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrElementExists   = errors.New("element already exists")
+	ErrElementNotFound = errors.New("element not found")
+	ErrInvalidElement  = errors.New("invalid element")
+)
+
+// Overlay holds the custom changes made through the API. It lives next to the
+// data file and is applied on top of it on every load, so the changes survive
+// a rescrape. Elements replace scraped elements of the same name or are added
+// after them; Deleted removes scraped elements.
+type Overlay struct {
+	Elements []Element `json:"elements"`
+	Deleted  []string  `json:"deleted"`
+}
+
+// InvalidChangeError rejects a change that would add validation errors the
+// data did not already have.
+type InvalidChangeError struct {
+	Issues []ValidationIssue `json:"issues"`
+}
+
+func (e *InvalidChangeError) Error() string {
+	if len(e.Issues) == 1 {
+		return fmt.Sprintf("invalid change: %s", e.Issues[0].Message)
+	}
+	return fmt.Sprintf("invalid change: %d validation errors, first: %s", len(e.Issues), e.Issues[0].Message)
+}
+
+// OverlayPath returns where the overlay of filePath is stored, e.g.
+// data/elements.overlay.json for data/elements.json.
+func OverlayPath(filePath string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".overlay.json"
+}
+
+func readOverlay(path string) (*Overlay, error) {
+	overlay := &Overlay{}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return overlay, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, overlay); err != nil {
+		return nil, fmt.Errorf("overlay %s: %w", path, err)
+	}
+	return overlay, nil
+}
+
+// writeOverlay replaces the overlay file in one rename so a crash never
+// leaves it half written.
+func writeOverlay(path string, overlay *Overlay) error {
+	data, err := json.MarshalIndent(overlay, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (o *Overlay) apply(elements []Element) []Element {
+	deleted := make(map[string]bool)
+	for _, name := range o.Deleted {
+		deleted[name] = true
+	}
+	custom := make(map[string]Element)
+	for _, element := range o.Elements {
+		custom[element.Name] = element
+	}
+
+	result := make([]Element, 0, len(elements)+len(o.Elements))
+	seen := make(map[string]bool)
+	for _, element := range elements {
+		if deleted[element.Name] {
+			continue
+		}
+		if replacement, exists := custom[element.Name]; exists {
+			if seen[element.Name] {
+				continue
+			}
+			element = replacement
+		}
+		seen[element.Name] = true
+		result = append(result, element)
+	}
+	for _, element := range o.Elements {
+		if !seen[element.Name] {
+			result = append(result, element)
+		}
+	}
+
+	return result
+}
+
+func (o *Overlay) put(element Element) {
+	o.remove(element.Name)
+	o.Elements = append(o.Elements, element)
+}
+
+func (o *Overlay) remove(name string) {
+	elements := o.Elements[:0]
+	for _, element := range o.Elements {
+		if element.Name != name {
+			elements = append(elements, element)
+		}
+	}
+	o.Elements = elements
+
+	deleted := o.Deleted[:0]
+	for _, deletedName := range o.Deleted {
+		if deletedName != name {
+			deleted = append(deleted, deletedName)
+		}
+	}
+	o.Deleted = deleted
+}
+
+func checkCustomElement(element Element) error {
+	if strings.TrimSpace(element.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidElement)
+	}
+	if element.Tier < 0 {
+		return fmt.Errorf("%w: tier must not be negative", ErrInvalidElement)
+	}
+	return nil
+}
+
+func (es *ElementsService) AddElement(element Element) (*ReloadDiff, error) {
+	if err := checkCustomElement(element); err != nil {
+		return nil, err
+	}
+	return es.changeOverlay(func(overlay *Overlay, current map[string]bool, scraped map[string]bool) error {
+		if current[element.Name] {
+			return fmt.Errorf("%w: '%s'", ErrElementExists, element.Name)
+		}
+		overlay.put(element)
+		return nil
+	})
+}
+
+func (es *ElementsService) UpdateElement(element Element) (*ReloadDiff, error) {
+	if err := checkCustomElement(element); err != nil {
+		return nil, err
+	}
+	return es.changeOverlay(func(overlay *Overlay, current map[string]bool, scraped map[string]bool) error {
+		if !current[element.Name] {
+			return fmt.Errorf("%w: '%s'", ErrElementNotFound, element.Name)
+		}
+		overlay.put(element)
+		return nil
+	})
+}
+
+// DeleteElement removes a custom element, or hides a scraped one until it is
+// added again.
+func (es *ElementsService) DeleteElement(name string) (*ReloadDiff, error) {
+	return es.changeOverlay(func(overlay *Overlay, current map[string]bool, scraped map[string]bool) error {
+		if !current[name] {
+			return fmt.Errorf("%w: '%s'", ErrElementNotFound, name)
+		}
+		overlay.remove(name)
+		if scraped[name] {
+			overlay.Deleted = append(overlay.Deleted, name)
+		}
+		return nil
+	})
+}
+
+// changeOverlay applies change to the overlay, rejects the result when it adds
+// validation errors, and otherwise saves the overlay and swaps in a dataset
+// rebuilt from it.
+func (es *ElementsService) changeOverlay(change func(overlay *Overlay, current map[string]bool, scraped map[string]bool) error) (*ReloadDiff, error) {
+	es.reloadMutex.Lock()
+	defer es.reloadMutex.Unlock()
+
+	es.mutex.RLock()
+	previous, filePath, opts := es.data, es.filePath, es.options
+	es.mutex.RUnlock()
+
+	if previous == nil {
+		return nil, errors.New("elements service is not initialized")
+	}
+
+	scrapedElements, err := ReadElements(filePath)
+	if err != nil {
+		return nil, err
+	}
+	overlayPath := OverlayPath(filePath)
+	overlay, err := readOverlay(overlayPath)
+	if err != nil {
+		return nil, err
+	}
+
+	before := overlay.apply(scrapedElements)
+	scraped := make(map[string]bool, len(scrapedElements))
+	for _, element := range scrapedElements {
+		scraped[element.Name] = true
+	}
+	current := make(map[string]bool, len(before))
+	for _, element := range before {
+		current[element.Name] = true
+	}
+
+	if err := change(overlay, current, scraped); err != nil {
+		return nil, err
+	}
+
+	after := overlay.apply(scrapedElements)
+	if issues := newErrors(ValidateElements(before), ValidateElements(after)); len(issues) > 0 {
+		return nil, &InvalidChangeError{Issues: issues}
+	}
+
+	if err := writeOverlay(overlayPath, overlay); err != nil {
+		return nil, err
+	}
+
+	data := newDataset(after, opts)
+	es.mutex.Lock()
+	es.data = data
+	es.mutex.Unlock()
+
+	return DiffDatasets(previous, data), nil
+}
+
+// newErrors returns the errors of after that before did not have.
+func newErrors(before *ValidationReport, after *ValidationReport) []ValidationIssue {
+	known := make(map[string]bool)
+	for _, issue := range before.Issues {
+		known[issueKey(issue)] = true
+	}
+
+	issues := []ValidationIssue{}
+	for _, issue := range after.Issues {
+		if issue.Severity == SeverityError && !known[issueKey(issue)] {
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+func issueKey(issue ValidationIssue) string {
+	return fmt.Sprint(issue.Kind, issue.Element, issue.Recipe, issue.Message)
+}
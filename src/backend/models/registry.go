@@ -0,0 +1,116 @@
+package elementsModel
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultDataset is the name of the dataset loaded from the scraped
+// elements.json and used when a request does not name one.
+const DefaultDataset = "default"
+
+var ErrUnknownDataset = errors.New("unknown dataset")
+
+// Registry holds the named datasets served side by side, each with its own
+// ElementsService and graph.
+type Registry struct {
+	services map[string]*ElementsService
+	mutex    sync.RWMutex
+}
+
+var (
+	registry *Registry
+	once     sync.Once
+)
+
+func GetRegistry() *Registry {
+	once.Do(func() {
+		registry = &Registry{
+			services: make(map[string]*ElementsService),
+		}
+	})
+	return registry
+}
+
+// Load registers name and loads its data from filePath. Loading a name that
+// is already registered does nothing, like Initialize.
+func (r *Registry) Load(name string, filePath string, opts LoadOptions) error {
+	if name == "" || strings.ContainsAny(name, "=,") {
+		return fmt.Errorf("invalid dataset name '%s'", name)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, exists := r.services[name]; exists {
+		return nil
+	}
+
+	service := NewElementsService()
+	if err := service.InitializeWith(filePath, opts); err != nil {
+		return fmt.Errorf("dataset '%s': %w", name, err)
+	}
+	r.services[name] = service
+	return nil
+}
+
+// Get returns the service of the named dataset, or of DefaultDataset when
+// name is empty.
+func (r *Registry) Get(name string) (*ElementsService, error) {
+	if name == "" {
+		name = DefaultDataset
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if service, exists := r.services[name]; exists {
+		return service, nil
+	}
+	return nil, fmt.Errorf("%w '%s'", ErrUnknownDataset, name)
+}
+
+func (r *Registry) Snapshot(name string) (*Dataset, error) {
+	service, err := r.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return service.Snapshot(), nil
+}
+
+func (r *Registry) Names() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	names := make([]string, 0, len(r.services))
+	for name := range r.services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseDatasets reads a list of datasets written as name=path pairs separated
+// by commas, e.g. "la2-2024=data/la2-2024.json,custom=data/custom.json".
+func ParseDatasets(value string) (map[string]string, error) {
+	datasets := make(map[string]string)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, path, ok := strings.Cut(entry, "=")
+		name, path = strings.TrimSpace(name), strings.TrimSpace(path)
+		if !ok || name == "" || path == "" {
+			return nil, fmt.Errorf("dataset '%s' must be written as name=path", entry)
+		}
+		if _, exists := datasets[name]; exists {
+			return nil, fmt.Errorf("dataset '%s' is listed more than once", name)
+		}
+		datasets[name] = path
+	}
+	return datasets, nil
+}
@@ -0,0 +1,158 @@
+package elementsModel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+type RecipeChange struct {
+	Element     string   `json:"element"`
+	Ingredients []string `json:"ingredients"`
+}
+
+type ReloadDiff struct {
+	ReloadedAt      time.Time      `json:"reloadedAt"`
+	Elements        int            `json:"elements"`
+	AddedElements   []string       `json:"addedElements"`
+	RemovedElements []string       `json:"removedElements"`
+	AddedRecipes    []RecipeChange `json:"addedRecipes"`
+	RemovedRecipes  []RecipeChange `json:"removedRecipes"`
+}
+
+func (d *ReloadDiff) String() string {
+	return fmt.Sprintf("%d elements (+%d/-%d), recipes +%d/-%d",
+		d.Elements, len(d.AddedElements), len(d.RemovedElements), len(d.AddedRecipes), len(d.RemovedRecipes))
+}
+
+// Reload reads the data file again with the options it was first loaded with
+// and swaps the new dataset in. Searches holding the previous Snapshot finish
+// on it. When the file cannot be loaded the current dataset is kept.
+func (es *ElementsService) Reload() (*ReloadDiff, error) {
+	es.reloadMutex.Lock()
+	defer es.reloadMutex.Unlock()
+
+	es.mutex.RLock()
+	previous, filePath, opts := es.data, es.filePath, es.options
+	es.mutex.RUnlock()
+
+	if previous == nil {
+		return nil, errors.New("elements service is not initialized")
+	}
+
+	data, err := loadDataset(filePath, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	es.mutex.Lock()
+	es.data = data
+	es.mutex.Unlock()
+
+	return DiffDatasets(previous, data), nil
+}
+
+// DiffDatasets lists the elements and recipes of next that are not in
+// previous and the other way round. Recipes are compared regardless of the
+// order of their ingredients.
+func DiffDatasets(previous *Dataset, next *Dataset) *ReloadDiff {
+	return &ReloadDiff{
+		ReloadedAt:      time.Now(),
+		Elements:        len(next.elements),
+		AddedElements:   elementsMissingFrom(next.elements, previous.elements),
+		RemovedElements: elementsMissingFrom(previous.elements, next.elements),
+		AddedRecipes:    recipesMissingFrom(next.elements, previous.elements),
+		RemovedRecipes:  recipesMissingFrom(previous.elements, next.elements),
+	}
+}
+
+// elementsMissingFrom returns the names of the elements of from that other
+// does not have.
+func elementsMissingFrom(from []Element, other []Element) []string {
+	known := make(map[string]bool)
+	for _, element := range other {
+		known[element.Name] = true
+	}
+
+	names := []string{}
+	for _, element := range from {
+		if !known[element.Name] {
+			known[element.Name] = true
+			names = append(names, element.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// recipesMissingFrom returns the recipes of from that other does not have.
+func recipesMissingFrom(from []Element, other []Element) []RecipeChange {
+	known := make(map[string]bool)
+	for _, element := range other {
+		for _, recipe := range element.Recipes {
+			known[recipeKey(element.Name, recipe)] = true
+		}
+	}
+
+	changes := []RecipeChange{}
+	for _, element := range from {
+		for _, recipe := range element.Recipes {
+			key := recipeKey(element.Name, recipe)
+			if known[key] {
+				continue
+			}
+			known[key] = true
+			changes = append(changes, RecipeChange{
+				Element:     element.Name,
+				Ingredients: recipe.Ingredients,
+			})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].Element < changes[j].Element
+	})
+	return changes
+}
+
+func recipeKey(element string, recipe Recipe) string {
+	ingredients := append([]string(nil), recipe.Ingredients...)
+	sort.Strings(ingredients)
+	return element + "=" + strings.Join(ingredients, "+")
+}
+
+// Watch polls the data file every interval and reloads it whenever its
+// modification time or size changes, passing the outcome of each reload to
+// onReload. It returns when ctx is done.
+func (es *ElementsService) Watch(ctx context.Context, interval time.Duration, onReload func(*ReloadDiff, error)) {
+	es.mutex.RLock()
+	filePath := es.filePath
+	es.mutex.RUnlock()
+
+	last, _ := os.Stat(filePath)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(filePath)
+		if err != nil {
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+
+		onReload(es.Reload())
+	}
+}
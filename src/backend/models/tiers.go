@@ -0,0 +1,210 @@
+package elementsModel
+
+import (
+	"fmt"
+	"sort"
+)
+
+type TierMode string
+
+const (
+	TierShortest TierMode = "shortest"
+	TierLongest  TierMode = "longest"
+)
+
+// unparsedTier is what the scraper assigns when a section title cannot be
+// read as a tier.
+const unparsedTier = 999
+
+func ParseTierMode(mode string) (TierMode, error) {
+	switch TierMode(mode) {
+	case "", TierShortest:
+		return TierShortest, nil
+	case TierLongest:
+		return TierLongest, nil
+	default:
+		return "", fmt.Errorf("unknown tier mode '%s'", mode)
+	}
+}
+
+type TierDiff struct {
+	Element  string `json:"element"`
+	Declared int    `json:"declared"`
+	Computed int    `json:"computed"`
+	Unparsed bool   `json:"unparsed,omitempty"`
+}
+
+type TierReport struct {
+	Mode        TierMode   `json:"mode"`
+	Elements    int        `json:"elements"`
+	Mismatches  []TierDiff `json:"mismatches"`
+	Unreachable []string   `json:"unreachable"`
+}
+
+// ComputeTiers derives tiers from the recipes alone, starting from the
+// elements declared as tier 0. The shortest tier of an element is one more
+// than the highest ingredient tier of its cheapest recipe, which is how the
+// game defines tiers. The longest tier is the length of the longest recipe
+// chain from tier 0 through the recipes whose ingredients all have a lower
+// shortest tier than the element, which form a DAG. Elements that cannot be
+// crafted from tier 0 are left out.
+func ComputeTiers(elements []Element, mode TierMode) map[string]int {
+	shortest := make(map[string]int)
+	for _, element := range elements {
+		if element.Tier == 0 {
+			shortest[element.Name] = 0
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, element := range elements {
+			if element.Tier == 0 {
+				continue
+			}
+			for _, recipe := range element.Recipes {
+				tier, ok := recipeTier(recipe, shortest)
+				if !ok {
+					continue
+				}
+				if current, exists := shortest[element.Name]; !exists || tier < current {
+					shortest[element.Name] = tier
+					changed = true
+				}
+			}
+		}
+	}
+
+	if mode != TierLongest {
+		return shortest
+	}
+	return longestTiers(elements, shortest)
+}
+
+// longestTiers walks the elements in shortest tier order, so the ingredients
+// of every recipe in the DAG are done before the element itself.
+func longestTiers(elements []Element, shortest map[string]int) map[string]int {
+	var byTier []Element
+	for _, element := range elements {
+		if _, exists := shortest[element.Name]; exists {
+			byTier = append(byTier, element)
+		}
+	}
+	sort.SliceStable(byTier, func(i, j int) bool {
+		return shortest[byTier[i].Name] < shortest[byTier[j].Name]
+	})
+
+	longest := make(map[string]int, len(shortest))
+	for _, element := range byTier {
+		tier := shortest[element.Name]
+		longest[element.Name] = tier
+		if tier == 0 {
+			continue
+		}
+		for _, recipe := range element.Recipes {
+			if !followsTierRule(recipe, tier, shortest) {
+				continue
+			}
+			if depth, ok := recipeTier(recipe, longest); ok && depth > longest[element.Name] {
+				longest[element.Name] = depth
+			}
+		}
+	}
+
+	return longest
+}
+
+func recipeTier(recipe Recipe, tiers map[string]int) (int, bool) {
+	if len(recipe.Ingredients) != 2 {
+		return 0, false
+	}
+
+	tier := 0
+	for _, ingredient := range recipe.Ingredients {
+		ingredientTier, exists := tiers[ingredient]
+		if !exists {
+			return 0, false
+		}
+		if ingredientTier+1 > tier {
+			tier = ingredientTier + 1
+		}
+	}
+	return tier, true
+}
+
+// followsTierRule reports whether every ingredient of recipe is below tier.
+func followsTierRule(recipe Recipe, tier int, tiers map[string]int) bool {
+	for _, ingredient := range recipe.Ingredients {
+		if ingredientTier, exists := tiers[ingredient]; !exists || ingredientTier >= tier {
+			return false
+		}
+	}
+	return true
+}
+
+// CompareTiers diffs the declared tiers against the ones computed for mode.
+func CompareTiers(elements []Element, mode TierMode) *TierReport {
+	computed := ComputeTiers(elements, mode)
+	report := &TierReport{
+		Mode:        mode,
+		Elements:    len(elements),
+		Mismatches:  []TierDiff{},
+		Unreachable: []string{},
+	}
+
+	for _, element := range elements {
+		tier, exists := computed[element.Name]
+		if !exists {
+			report.Unreachable = append(report.Unreachable, element.Name)
+			continue
+		}
+		if tier != element.Tier {
+			report.Mismatches = append(report.Mismatches, TierDiff{
+				Element:  element.Name,
+				Declared: element.Tier,
+				Computed: tier,
+				Unparsed: element.Tier == unparsedTier,
+			})
+		}
+	}
+
+	sort.Slice(report.Mismatches, func(i, j int) bool {
+		if report.Mismatches[i].Computed != report.Mismatches[j].Computed {
+			return report.Mismatches[i].Computed < report.Mismatches[j].Computed
+		}
+		return report.Mismatches[i].Element < report.Mismatches[j].Element
+	})
+	sort.Strings(report.Unreachable)
+
+	return report
+}
+
+// GetTierReport compares the tiers as they were in the data file with the
+// ones computed for mode, even when the graph was loaded with recomputed
+// tiers.
+func (es *ElementsService) GetTierReport(mode TierMode) *TierReport {
+	return es.Snapshot().GetTierReport(mode)
+}
+
+func (d *Dataset) GetTierReport(mode TierMode) *TierReport {
+	declared := make([]Element, len(d.elements))
+	for i, element := range d.elements {
+		declared[i] = element
+		if tier, exists := d.declaredTiers[element.Name]; exists {
+			declared[i].Tier = tier
+		}
+	}
+
+	return CompareTiers(declared, mode)
+}
+
+// recomputeTiers replaces the declared tier of every element that can be
+// crafted from tier 0. Unreachable elements keep their declared tier.
+func recomputeTiers(elements []Element, mode TierMode) {
+	computed := ComputeTiers(elements, mode)
+	for i := range elements {
+		if tier, exists := computed[elements[i].Name]; exists {
+			elements[i].Tier = tier
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package elementsModel
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestComputeTiersCorrectsUnparsedTiers checks that elements using an
+// ingredient the scraper left at tier 999 get a tier from the recipes instead
+// of losing those recipes.
+func TestComputeTiersCorrectsUnparsedTiers(t *testing.T) {
+	recipe := func(a, b string) Recipe { return Recipe{Ingredients: []string{a, b}} }
+	elements := []Element{
+		{Name: "A", Tier: 0},
+		{Name: "B", Tier: 0},
+		{Name: "C", Tier: 1, Recipes: []Recipe{recipe("A", "B")}},
+		{Name: "X", Tier: unparsedTier, Recipes: []Recipe{recipe("C", "A")}},
+		{Name: "D", Tier: 2, Recipes: []Recipe{recipe("X", "B"), recipe("C", "C")}},
+		{Name: "E", Tier: 2, Recipes: []Recipe{recipe("X", "X"), recipe("E", "A")}},
+		{Name: "F", Tier: 4, Recipes: []Recipe{recipe("G", "A")}},
+	}
+	want := map[string]int{"A": 0, "B": 0, "C": 1, "X": 2, "D": 2, "E": 3}
+
+	for _, mode := range []TierMode{TierShortest, TierLongest} {
+		if got := ComputeTiers(elements, mode); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %v, want %v", mode, got, want)
+		}
+	}
+
+	report := CompareTiers(elements, TierLongest)
+	wantMismatches := []TierDiff{
+		{Element: "X", Declared: unparsedTier, Computed: 2, Unparsed: true},
+		{Element: "E", Declared: 2, Computed: 3},
+	}
+	if !reflect.DeepEqual(report.Mismatches, wantMismatches) {
+		t.Errorf("got mismatches %+v, want %+v", report.Mismatches, wantMismatches)
+	}
+	if want := []string{"F"}; !reflect.DeepEqual(report.Unreachable, want) {
+		t.Errorf("got unreachable %v, want %v", report.Unreachable, want)
+	}
+}
@@ -0,0 +1,227 @@
+package elementsModel
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+type IssueKind string
+
+const (
+	IssueDuplicateName     IssueKind = "duplicate name"
+	IssueMissingIngredient IssueKind = "missing ingredient"
+	IssueArity             IssueKind = "wrong arity"
+	IssueTierMismatch      IssueKind = "tier mismatch"
+	IssueUnreachable       IssueKind = "unreachable"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+type ValidationIssue struct {
+	Kind     IssueKind `json:"kind"`
+	Severity Severity  `json:"severity"`
+	Element  string    `json:"element"`
+	Recipe   []string  `json:"recipe,omitempty"`
+	Message  string    `json:"message"`
+}
+
+type ValidationReport struct {
+	Elements int               `json:"elements"`
+	Recipes  int               `json:"recipes"`
+	Errors   int               `json:"errors"`
+	Warnings int               `json:"warnings"`
+	Issues   []ValidationIssue `json:"issues"`
+}
+
+func (r *ValidationReport) HasErrors() bool {
+	return r.Errors > 0
+}
+
+func (r *ValidationReport) add(issue ValidationIssue) {
+	if issue.Severity == SeverityError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+	r.Issues = append(r.Issues, issue)
+}
+
+func (es *ElementsService) GetValidationReport() *ValidationReport {
+	return es.Snapshot().GetValidationReport()
+}
+
+func (d *Dataset) GetValidationReport() *ValidationReport {
+	return d.validation
+}
+
+func ValidateFile(filePath string) (*ValidationReport, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var elements []Element
+	if err := json.Unmarshal(data, &elements); err != nil {
+		return nil, err
+	}
+
+	return ValidateElements(elements), nil
+}
+
+// ValidateElements checks element data before it is turned into a graph.
+// Duplicate names, unknown ingredients, recipes that do not take exactly two
+// ingredients and elements that cannot be crafted from tier 0 are errors. A
+// declared tier that differs from one more than the lowest tier any recipe
+// allows is a warning, since the graph still builds from the declared tiers.
+func ValidateElements(elements []Element) *ValidationReport {
+	report := &ValidationReport{Elements: len(elements), Issues: []ValidationIssue{}}
+
+	byName := make(map[string]*Element)
+	for i := range elements {
+		element := &elements[i]
+		report.Recipes += len(element.Recipes)
+		if _, exists := byName[element.Name]; exists {
+			report.add(ValidationIssue{
+				Kind:     IssueDuplicateName,
+				Severity: SeverityError,
+				Element:  element.Name,
+				Message:  fmt.Sprintf("element '%s' is defined more than once", element.Name),
+			})
+			continue
+		}
+		byName[element.Name] = element
+	}
+
+	for i := range elements {
+		element := &elements[i]
+		for _, recipe := range element.Recipes {
+			if len(recipe.Ingredients) != 2 {
+				report.add(ValidationIssue{
+					Kind:     IssueArity,
+					Severity: SeverityError,
+					Element:  element.Name,
+					Recipe:   recipe.Ingredients,
+					Message:  fmt.Sprintf("recipe has %d ingredients instead of 2", len(recipe.Ingredients)),
+				})
+			}
+			for _, ingredient := range recipe.Ingredients {
+				if _, exists := byName[ingredient]; !exists {
+					report.add(ValidationIssue{
+						Kind:     IssueMissingIngredient,
+						Severity: SeverityError,
+						Element:  element.Name,
+						Recipe:   recipe.Ingredients,
+						Message:  fmt.Sprintf("ingredient '%s' is not a known element", ingredient),
+					})
+				}
+			}
+		}
+	}
+
+	for i := range elements {
+		element := &elements[i]
+		if element.Tier == 0 || byName[element.Name] != element {
+			continue
+		}
+		if minTier, ok := lowestRecipeTier(element, byName); ok && minTier != element.Tier {
+			report.add(ValidationIssue{
+				Kind:     IssueTierMismatch,
+				Severity: SeverityWarning,
+				Element:  element.Name,
+				Message:  fmt.Sprintf("declared tier %d but its recipes allow tier %d", element.Tier, minTier),
+			})
+		}
+	}
+
+	reachable := reachableFromTier0(elements, byName)
+	for i := range elements {
+		element := &elements[i]
+		if reachable[element.Name] || byName[element.Name] != element {
+			continue
+		}
+		report.add(ValidationIssue{
+			Kind:     IssueUnreachable,
+			Severity: SeverityError,
+			Element:  element.Name,
+			Message:  "element cannot be crafted starting from the tier 0 elements",
+		})
+	}
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		if report.Issues[i].Severity != report.Issues[j].Severity {
+			return report.Issues[i].Severity == SeverityError
+		}
+		return report.Issues[i].Element < report.Issues[j].Element
+	})
+
+	return report
+}
+
+// lowestRecipeTier returns one more than the highest ingredient tier of the
+// cheapest well formed recipe of element whose ingredients are all known.
+func lowestRecipeTier(element *Element, byName map[string]*Element) (int, bool) {
+	lowest, found := 0, false
+	for _, recipe := range element.Recipes {
+		tier, known := 0, len(recipe.Ingredients) == 2
+		for _, ingredient := range recipe.Ingredients {
+			source, exists := byName[ingredient]
+			if !exists {
+				known = false
+				break
+			}
+			if source.Tier+1 > tier {
+				tier = source.Tier + 1
+			}
+		}
+		if known && (!found || tier < lowest) {
+			lowest, found = tier, true
+		}
+	}
+	return lowest, found
+}
+
+func reachableFromTier0(elements []Element, byName map[string]*Element) map[string]bool {
+	reachable := make(map[string]bool)
+	for _, element := range elements {
+		if element.Tier == 0 {
+			reachable[element.Name] = true
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, element := range byName {
+			if reachable[element.Name] {
+				continue
+			}
+			for _, recipe := range element.Recipes {
+				if recipeAvailable(recipe, reachable) {
+					reachable[element.Name] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+
+	return reachable
+}
+
+func recipeAvailable(recipe Recipe, available map[string]bool) bool {
+	if len(recipe.Ingredients) != 2 {
+		return false
+	}
+	for _, ingredient := range recipe.Ingredients {
+		if !available[ingredient] {
+			return false
+		}
+	}
+	return true
+}
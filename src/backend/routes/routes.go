@@ -2,12 +2,19 @@ package routes
 
 import (
 	elementsController "backend/controllers"
+	elementsModel "backend/models"
 	"backend/websocket"
+	"context"
+	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"os"
 	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -20,7 +27,7 @@ func InitRoutes() http.Handler {
 
     r.Use(cors.Handler(cors.Options{
         AllowedOrigins:   []string{"*"},
-        AllowedMethods:   []string{"GET", "POST", "OPTIONS"},
+        AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
         AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
         AllowCredentials: true,
     }))
@@ -31,8 +38,26 @@ func InitRoutes() http.Handler {
     }
 
     r.Route("/api", func(r chi.Router) {
+        r.Get("/datasets", handleGetDatasets(controller))
         r.Get("/tiers", handleGetAllElementsTiers)
+        r.Get("/tiers/computed", handleGetTierReport(controller))
         r.Get("/elements/{name}", handleGetElementByName(controller))
+        r.Get("/elements/{name}/count", handleGetRecipeCount(controller))
+        r.Get("/elements/{name}/uses", handleGetElementUses(controller))
+        r.Get("/elements/{name}/icon", handleGetElementIcon(controller))
+        r.Get("/chains", handleGetChains)
+        r.Get("/integrity", handleGetIntegrityReport(controller))
+        r.Get("/changelog", handleGetChangelog(controller))
+        r.Get("/search", handleSearch)
+        r.Post("/craftable", handleGetCraftable(controller))
+        r.Post("/plan", handleBuildCraftingPlan)
+        r.Get("/plan/complete", handleGetCompletionPlan(controller))
+
+        admin := requireAdminToken(os.Getenv("ADMIN_TOKEN"))
+        r.With(admin).Post("/admin/reload", handleReload(controller))
+        r.With(admin).Post("/elements", handleAddElement(controller))
+        r.With(admin).Put("/elements/{name}", handleUpdateElement(controller))
+        r.With(admin).Delete("/elements/{name}", handleDeleteElement(controller))
     })
 
     r.Get("/ws/tree", websocket.HandleTreeWebSocket(controller))
@@ -51,7 +76,7 @@ func handleGetElementByName(controller *elementsController.ElementController) ht
             return
         }
 
-        element, err := controller.GetElementByName(name)
+        element, err := controller.GetElementByName(r.URL.Query().Get("dataset"), name)
         if err != nil {
             http.Error(w, err.Error(), http.StatusNotFound)
             return
@@ -62,6 +87,332 @@ func handleGetElementByName(controller *elementsController.ElementController) ht
     }
 }
 
+func handleGetRecipeCount(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        name := chi.URLParam(r, "name")
+        if name == "" {
+            http.Error(w, "element name is required", http.StatusBadRequest)
+            return
+        }
+
+        count, err := controller.GetRecipeCount(r.URL.Query().Get("dataset"), name)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(count)
+    }
+}
+
+func handleGetElementIcon(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        path, err := controller.GetIconPath(r.URL.Query().Get("dataset"), chi.URLParam(r, "name"))
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        w.Header().Set("Cache-Control", "public, max-age=86400")
+        http.ServeFile(w, r, path)
+    }
+}
+
+func handleGetCraftable(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var req struct {
+            Elements []string `json:"elements"`
+            Closure  bool     `json:"closure"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "invalid request body", http.StatusBadRequest)
+            return
+        }
+        if len(req.Elements) == 0 {
+            http.Error(w, "elements are required", http.StatusBadRequest)
+            return
+        }
+
+        result, err := controller.GetCraftable(r.URL.Query().Get("dataset"), req.Elements, req.Closure)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(result)
+    }
+}
+
+func handleBuildCraftingPlan(w http.ResponseWriter, r *http.Request) {
+    var tree elementsController.TreeNode
+    if err := json.NewDecoder(r.Body).Decode(&tree); err != nil || tree.Name == "" {
+        http.Error(w, "request body must be a recipe tree", http.StatusBadRequest)
+        return
+    }
+    if err := elementsController.ValidateTree(&tree); err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    var response interface{}
+    if tree.Name == "Root" {
+        response = elementsController.BuildCraftingPlans(&tree)
+    } else {
+        response = elementsController.BuildCraftingPlan(&tree)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(response)
+}
+
+func handleGetCompletionPlan(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        plan, err := controller.GetCompletionPlan(r.URL.Query().Get("dataset"))
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        if r.URL.Query().Get("format") == "text" {
+            w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+            w.Write([]byte(plan.Text))
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(plan)
+    }
+}
+
+func handleSearch(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    target := query.Get("target")
+    if target == "" {
+        http.Error(w, "target is required", http.StatusBadRequest)
+        return
+    }
+
+    count := 1
+    if countStr := query.Get("count"); countStr != "" {
+        parsed, err := strconv.Atoi(countStr)
+        if err != nil || parsed <= 0 {
+            http.Error(w, "count must be a positive integer", http.StatusBadRequest)
+            return
+        }
+        count = parsed
+    }
+
+    opts := elementsController.SearchOptions{Dataset: query.Get("dataset")}
+    for name, field := range map[string]*int{"workers": &opts.Workers, "maxDepth": &opts.MaxDepth, "maxTier": &opts.MaxTier} {
+        value, err := parseNonNegative(query.Get(name))
+        if err != nil {
+            http.Error(w, name+" must be a non-negative integer", http.StatusBadRequest)
+            return
+        }
+        *field = value
+    }
+    opts.Exclude = splitList(query.Get("exclude"))
+    opts.Require = splitList(query.Get("require"))
+    opts.AllowCycles = query.Get("allowCycles") == "true"
+
+    timeout, err := parseNonNegative(query.Get("timeout"))
+    if err != nil {
+        http.Error(w, "timeout must be a non-negative number of milliseconds", http.StatusBadRequest)
+        return
+    }
+    ctx, cancel := context.WithCancel(r.Context())
+    if timeout > 0 {
+        ctx, cancel = context.WithTimeout(r.Context(), time.Duration(timeout)*time.Millisecond)
+    }
+    defer cancel()
+
+    multiThread := query.Get("multiThread") == "true"
+    tree, stats, searchDuration, err := elementsController.StartSearch(ctx, query.Get("mode"), multiThread, target, count, opts, nil)
+    if errors.Is(err, elementsController.ErrInvalidSearch) {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if tree == nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    response := map[string]interface{}{
+        "stats":          stats,
+        "searchDuration": searchDuration,
+        "status":         elementsController.SearchStatus(err),
+    }
+    if err != nil {
+        response["error"] = err.Error()
+    }
+    if query.Get("format") == "dag" {
+        response["dag"] = elementsController.BuildDAG(tree)
+    } else {
+        response["tree"] = tree
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(response)
+}
+
+func parseNonNegative(value string) (int, error) {
+    if value == "" {
+        return 0, nil
+    }
+
+    parsed, err := strconv.Atoi(value)
+    if err != nil || parsed < 0 {
+        return 0, errors.New("not a non-negative integer")
+    }
+    return parsed, nil
+}
+
+func splitList(value string) []string {
+    if value == "" {
+        return nil
+    }
+
+    var items []string
+    for _, item := range strings.Split(value, ",") {
+        if item = strings.TrimSpace(item); item != "" {
+            items = append(items, item)
+        }
+    }
+    return items
+}
+
+func handleGetElementUses(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        name := chi.URLParam(r, "name")
+        if name == "" {
+            http.Error(w, "element name is required", http.StatusBadRequest)
+            return
+        }
+
+        opts := elementsController.UsesOptions{
+            IncludeDropped: r.URL.Query().Get("includeDropped") == "true",
+        }
+        if tierStr := r.URL.Query().Get("tier"); tierStr != "" {
+            tier, err := strconv.Atoi(tierStr)
+            if err != nil {
+                http.Error(w, "tier must be an integer", http.StatusBadRequest)
+                return
+            }
+            opts.Tier = &tier
+        }
+
+        uses, err := controller.GetElementUses(r.URL.Query().Get("dataset"), name, opts)
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(uses)
+    }
+}
+
+func handleGetIntegrityReport(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        report, err := controller.GetIntegrityReport(r.URL.Query().Get("dataset"))
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+
+        if reason := r.URL.Query().Get("reason"); reason != "" {
+            filtered := *report
+            filtered.Dropped = nil
+            for _, dropped := range report.Dropped {
+                if string(dropped.Reason) == reason {
+                    filtered.Dropped = append(filtered.Dropped, dropped)
+                }
+            }
+            report = &filtered
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(report)
+    }
+}
+
+func handleGetChangelog(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        changelog, err := controller.GetChangelog(r.URL.Query().Get("dataset"))
+        if errors.Is(err, elementsModel.ErrUnknownDataset) || errors.Is(err, elementsModel.ErrNoChangelog) {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(changelog)
+    }
+}
+
+func handleGetTierReport(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        report, err := controller.GetTierReport(r.URL.Query().Get("dataset"), r.URL.Query().Get("mode"))
+        if errors.Is(err, elementsModel.ErrUnknownDataset) {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(report)
+    }
+}
+
+func handleGetChains(w http.ResponseWriter, r *http.Request) {
+    source := r.URL.Query().Get("source")
+    target := r.URL.Query().Get("target")
+    if source == "" || target == "" {
+        http.Error(w, "source and target are required", http.StatusBadRequest)
+        return
+    }
+
+    count := 1
+    if countStr := r.URL.Query().Get("count"); countStr != "" {
+        parsed, err := strconv.Atoi(countStr)
+        if err != nil || parsed <= 0 {
+            http.Error(w, "count must be a positive integer", http.StatusBadRequest)
+            return
+        }
+        count = parsed
+    }
+
+    chains, stats, searchDuration, err := elementsController.StartBidirectional(r.Context(), r.URL.Query().Get("dataset"), source, target, count)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+
+    response := map[string]interface{}{
+        "chains":         chains,
+        "stats":          stats,
+        "searchDuration": searchDuration,
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(response)
+}
+
+func handleGetDatasets(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(controller.GetDatasets())
+    }
+}
+
 func handleGetAllElementsTiers(w http.ResponseWriter, r *http.Request) {
     controller, err := elementsController.NewElementController("data/elements.json")
     if err != nil {
@@ -69,7 +420,11 @@ func handleGetAllElementsTiers(w http.ResponseWriter, r *http.Request) {
         return
     }
 
-    tierGroups, err := controller.GetAllElementsTiers()
+    tierGroups, err := controller.GetAllElementsTiers(r.URL.Query().Get("dataset"))
+    if errors.Is(err, elementsModel.ErrUnknownDataset) {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
@@ -92,4 +447,129 @@ func extractTierNumbers(tierGroups map[string][]string) []int {
     }
     sort.Ints(tiers)
     return tiers
-}
\ No newline at end of file
+}
+
+// requireAdminToken only lets through requests carrying the token as a bearer
+// token. Without a configured token the admin endpoints are disabled.
+func requireAdminToken(token string) func(http.Handler) http.Handler {
+    return func(next http.Handler) http.Handler {
+        return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+            if token == "" {
+                http.Error(w, "admin endpoints are disabled, set ADMIN_TOKEN to enable them", http.StatusForbidden)
+                return
+            }
+            given := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+            if subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+                http.Error(w, "invalid admin token", http.StatusUnauthorized)
+                return
+            }
+            next.ServeHTTP(w, r)
+        })
+    }
+}
+
+func handleReload(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        dataset := r.URL.Query().Get("dataset")
+        diff, err := controller.Reload(dataset)
+        if errors.Is(err, elementsModel.ErrUnknownDataset) {
+            http.Error(w, err.Error(), http.StatusNotFound)
+            return
+        }
+        if err != nil {
+            http.Error(w, "reload failed: "+err.Error(), http.StatusInternalServerError)
+            return
+        }
+
+        if dataset == "" {
+            dataset = elementsModel.DefaultDataset
+        }
+        log.Printf("Reloaded dataset %s: %s", dataset, diff)
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(diff)
+    }
+}
+
+func handleAddElement(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        var element elementsModel.Element
+        if err := json.NewDecoder(r.Body).Decode(&element); err != nil {
+            http.Error(w, "request body must be an element", http.StatusBadRequest)
+            return
+        }
+
+        diff, err := controller.AddElement(r.URL.Query().Get("dataset"), element)
+        if err != nil {
+            writeChangeError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusCreated)
+        json.NewEncoder(w).Encode(diff)
+    }
+}
+
+func handleUpdateElement(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        name := chi.URLParam(r, "name")
+        var element elementsModel.Element
+        if err := json.NewDecoder(r.Body).Decode(&element); err != nil {
+            http.Error(w, "request body must be an element", http.StatusBadRequest)
+            return
+        }
+        if element.Name == "" {
+            element.Name = name
+        }
+        if element.Name != name {
+            http.Error(w, "element name in the body does not match the URL", http.StatusBadRequest)
+            return
+        }
+
+        diff, err := controller.UpdateElement(r.URL.Query().Get("dataset"), element)
+        if err != nil {
+            writeChangeError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(diff)
+    }
+}
+
+func handleDeleteElement(controller *elementsController.ElementController) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        diff, err := controller.DeleteElement(r.URL.Query().Get("dataset"), chi.URLParam(r, "name"))
+        if err != nil {
+            writeChangeError(w, err)
+            return
+        }
+
+        w.Header().Set("Content-Type", "application/json")
+        json.NewEncoder(w).Encode(diff)
+    }
+}
+
+// writeChangeError maps the errors of the custom element endpoints to status
+// codes. Validation failures are sent as JSON so clients get every issue.
+func writeChangeError(w http.ResponseWriter, err error) {
+    var invalid *elementsModel.InvalidChangeError
+    switch {
+    case errors.As(err, &invalid):
+        w.Header().Set("Content-Type", "application/json")
+        w.WriteHeader(http.StatusUnprocessableEntity)
+        json.NewEncoder(w).Encode(map[string]interface{}{
+            "error":  err.Error(),
+            "issues": invalid.Issues,
+        })
+    case errors.Is(err, elementsModel.ErrUnknownDataset), errors.Is(err, elementsModel.ErrElementNotFound):
+        http.Error(w, err.Error(), http.StatusNotFound)
+    case errors.Is(err, elementsModel.ErrElementExists):
+        http.Error(w, err.Error(), http.StatusConflict)
+    case errors.Is(err, elementsModel.ErrInvalidElement):
+        http.Error(w, err.Error(), http.StatusBadRequest)
+    default:
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,305 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+)
+
+const detailWorkers = 4
+
+type DetailOptions struct {
+    // IconDir is where icons are cached. Icons already in it are not
+    // downloaded again.
+    IconDir string
+    // Previous holds the elements of an earlier scrape. Elements whose page
+    // was already read there reuse its details instead of visiting it again.
+    Previous []Element
+    // Offline never visits the wiki; elements only get details from Previous
+    // and icons already in IconDir.
+    Offline bool
+}
+
+type FailedDetail struct {
+    Element string `json:"element"`
+    Reason  string `json:"reason"`
+}
+
+// DetailReport is kept apart from the problems of the element list, since an
+// element without a description or icon is still usable.
+type DetailReport struct {
+    PagesVisited    int            `json:"pagesVisited"`
+    IconsDownloaded int            `json:"iconsDownloaded"`
+    IconsCached     int            `json:"iconsCached"`
+    Failed          []FailedDetail `json:"failed"`
+}
+
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// elementLink returns the wiki page of the element named in cell, preferring
+// the link whose text is the name over the link around its picture.
+func elementLink(cell *goquery.Selection, name string) string {
+    var link string
+    cell.Find("a[href]").EachWithBreak(func(_ int, a *goquery.Selection) bool {
+        href, _ := a.Attr("href")
+        if strings.Contains(href, "File:") {
+            return true
+        }
+        if link == "" || strings.TrimSpace(a.Text()) == name {
+            link = href
+        }
+        return strings.TrimSpace(a.Text()) != name
+    })
+    return absoluteURL(link)
+}
+
+// imageURL reads the source of img, which the wiki lazy loads through
+// data-src with a placeholder in src.
+func imageURL(img *goquery.Selection) string {
+    for _, attr := range []string{"data-src", "src"} {
+        if src, ok := img.Attr(attr); ok && src != "" && !strings.HasPrefix(src, "data:") {
+            return absoluteURL(src)
+        }
+    }
+    return ""
+}
+
+func absoluteURL(ref string) string {
+    if ref == "" {
+        return ""
+    }
+    base, _ := url.Parse(WikiURL)
+    resolved, err := base.Parse(ref)
+    if err != nil {
+        return ""
+    }
+    return resolved.String()
+}
+
+// ScrapeDetails fills in the link, icon and description of elements by
+// following each element's link on the wiki, and downloads icons to the cache.
+func ScrapeDetails(elements []Element, opts DetailOptions) DetailReport {
+    report := DetailReport{Failed: []FailedDetail{}}
+    if opts.IconDir != "" {
+        if err := os.MkdirAll(opts.IconDir, 0755); err != nil {
+            report.Failed = append(report.Failed, FailedDetail{Reason: err.Error()})
+            opts.IconDir = ""
+        }
+    }
+
+    previous := make(map[string]Element)
+    for _, element := range opts.Previous {
+        previous[element.Name] = element
+    }
+
+    var pending []int
+    for i := range elements {
+        element := &elements[i]
+        if known, exists := previous[element.Name]; exists && (known.DetailsScraped || known.Description != "") {
+            element.Description = known.Description
+            element.DetailsScraped = true
+            if element.Link == "" {
+                element.Link = known.Link
+            }
+            if known.IconURL != "" {
+                element.IconURL = known.IconURL
+            }
+            continue
+        }
+        if !opts.Offline && element.Link != "" {
+            pending = append(pending, i)
+        }
+    }
+
+    var mutex sync.Mutex
+    fail := func(name string, reason string) {
+        mutex.Lock()
+        defer mutex.Unlock()
+        report.Failed = append(report.Failed, FailedDetail{Element: name, Reason: reason})
+    }
+
+    if len(pending) > 0 {
+        c := colly.NewCollector(
+            colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"),
+            colly.AllowedDomains("little-alchemy.fandom.com"),
+            colly.Async(true),
+        )
+        c.Limit(&colly.LimitRule{DomainGlob: "*", Parallelism: detailWorkers})
+
+        c.OnResponse(func(r *colly.Response) {
+            element := &elements[r.Ctx.GetAny("index").(int)]
+            page, err := goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+            if err != nil {
+                fail(element.Name, err.Error())
+                return
+            }
+            description, icon := parseDetails(page)
+            element.Description = description
+            element.DetailsScraped = true
+            if icon != "" {
+                element.IconURL = icon
+            }
+            mutex.Lock()
+            report.PagesVisited++
+            mutex.Unlock()
+        })
+        c.OnError(func(r *colly.Response, err error) {
+            fail(elements[r.Ctx.GetAny("index").(int)].Name, err.Error())
+        })
+
+        for _, i := range pending {
+            ctx := colly.NewContext()
+            ctx.Put("index", i)
+            if err := c.Request("GET", elements[i].Link, nil, ctx, nil); err != nil {
+                fail(elements[i].Name, err.Error())
+            }
+        }
+        c.Wait()
+    }
+
+    if opts.IconDir == "" {
+        return report
+    }
+
+    client := &http.Client{Timeout: 30 * time.Second}
+    downloads := make(chan int)
+    var wg sync.WaitGroup
+    for range detailWorkers {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for i := range downloads {
+                element := &elements[i]
+                icon, err := downloadIcon(client, element.IconURL, opts.IconDir, element.Name)
+                if err != nil {
+                    fail(element.Name, err.Error())
+                    continue
+                }
+                element.Icon = icon
+                mutex.Lock()
+                report.IconsDownloaded++
+                mutex.Unlock()
+            }
+        }()
+    }
+
+    for i := range elements {
+        element := &elements[i]
+        if cached := cachedIcon(opts.IconDir, element.Name); cached != "" {
+            element.Icon = cached
+            report.IconsCached++
+            continue
+        }
+        if opts.Offline || element.IconURL == "" {
+            continue
+        }
+        downloads <- i
+    }
+    close(downloads)
+    wg.Wait()
+
+    return report
+}
+
+// parseDetails reads the description and icon of an element page. The
+// description is taken from the infobox when it has one and otherwise from
+// the first paragraph of the article.
+func parseDetails(page *goquery.Document) (string, string) {
+    description := strings.TrimSpace(page.Find(`.portable-infobox [data-source="description"] .pi-data-value`).First().Text())
+    if description == "" {
+        page.Find(".mw-parser-output > p").EachWithBreak(func(_ int, p *goquery.Selection) bool {
+            description = strings.Join(strings.Fields(p.Text()), " ")
+            return description == ""
+        })
+    }
+
+    icon := imageURL(page.Find(".portable-infobox .pi-image img").First())
+    return description, icon
+}
+
+// iconFileName is the name under which the icon of element is cached,
+// without its extension.
+func iconFileName(name string) string {
+    return strings.Trim(unsafeFileChars.ReplaceAllString(name, "_"), "_")
+}
+
+func cachedIcon(dir string, name string) string {
+    matches, _ := filepath.Glob(filepath.Join(dir, iconFileName(name)+".*"))
+    for _, match := range matches {
+        if !strings.HasSuffix(match, ".tmp") {
+            return filepath.Base(match)
+        }
+    }
+    return ""
+}
+
+func downloadIcon(client *http.Client, iconURL string, dir string, name string) (string, error) {
+    req, err := http.NewRequest("GET", iconURL, nil)
+    if err != nil {
+        return "", err
+    }
+    req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36")
+
+    resp, err := client.Do(req)
+    if err != nil {
+        return "", err
+    }
+    defer resp.Body.Close()
+    if resp.StatusCode != http.StatusOK {
+        return "", fmt.Errorf("downloading icon: %s", resp.Status)
+    }
+
+    file := iconFileName(name) + iconExtension(resp.Header.Get("Content-Type"), iconURL)
+    tmp := filepath.Join(dir, file+".tmp")
+    out, err := os.Create(tmp)
+    if err != nil {
+        return "", err
+    }
+    if _, err := io.Copy(out, resp.Body); err != nil {
+        out.Close()
+        os.Remove(tmp)
+        return "", err
+    }
+    if err := out.Close(); err != nil {
+        os.Remove(tmp)
+        return "", err
+    }
+    return file, os.Rename(tmp, filepath.Join(dir, file))
+}
+
+// iconExtension picks the file extension from the content type, falling back
+// to the URL. Wiki image URLs keep the file name before a /revision/ suffix.
+func iconExtension(contentType string, iconURL string) string {
+    switch strings.TrimSpace(strings.Split(contentType, ";")[0]) {
+    case "image/png":
+        return ".png"
+    case "image/svg+xml":
+        return ".svg"
+    case "image/jpeg":
+        return ".jpg"
+    case "image/gif":
+        return ".gif"
+    case "image/webp":
+        return ".webp"
+    }
+
+    if parsed, err := url.Parse(iconURL); err == nil {
+        name, _, _ := strings.Cut(parsed.Path, "/revision/")
+        if ext := path.Ext(name); ext != "" {
+            return strings.ToLower(ext)
+        }
+    }
+    return ".png"
+}
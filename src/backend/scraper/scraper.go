@@ -1,10 +1,11 @@
 package scraper
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
-	"log"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -16,26 +17,216 @@ type Recipe struct {
     Ingredients []string `json:"ingredients"`
 }
 
+// Element is one row of the wiki element list. Link, IconURL, Icon and
+// Description are filled in by ScrapeDetails; Icon is the file name of the
+// icon in the icon cache directory, and DetailsScraped records that the
+// element's page was read, even when it had no description.
 type Element struct {
-    Name    string   `json:"name"`
-    Tier    int      `json:"tier"`
-    Recipes []Recipe `json:"recipes"`
+    Name           string   `json:"name"`
+    Tier           int      `json:"tier"`
+    Recipes        []Recipe `json:"recipes"`
+    Link           string   `json:"link,omitempty"`
+    IconURL        string   `json:"iconUrl,omitempty"`
+    Icon           string   `json:"icon,omitempty"`
+    Description    string   `json:"description,omitempty"`
+    DetailsScraped bool     `json:"detailsScraped,omitempty"`
 }
 
-func Scrape(filePath string) {
+const WikiURL = "https://little-alchemy.fandom.com/wiki/Elements_(Little_Alchemy_2)"
+
+// unparsedTier is the tier given to elements whose section title cannot be
+// read as a tier.
+const unparsedTier = 999
+
+type FailedRow struct {
+    Section string `json:"section"`
+    Row     int    `json:"row"`
+    Text    string `json:"text"`
+    Reason  string `json:"reason"`
+}
+
+type UnexpectedSection struct {
+    ID    string `json:"id"`
+    Title string `json:"title"`
+}
+
+type ShortRecipe struct {
+    Element string `json:"element"`
+    Text    string `json:"text"`
+}
+
+// ScrapeReport lists everything the parser could not read cleanly. Elements
+// from unexpected sections get tier 999 and short recipes are left out.
+type ScrapeReport struct {
+    Source             string              `json:"source"`
+    Pages              int                 `json:"pages"`
+    Elements           int                 `json:"elements"`
+    Recipes            int                 `json:"recipes"`
+    FailedRows         []FailedRow         `json:"failedRows"`
+    UnexpectedSections []UnexpectedSection `json:"unexpectedSections"`
+    ShortRecipes       []ShortRecipe       `json:"shortRecipes"`
+    Duplicates         []string            `json:"duplicates"`
+    Details            *DetailReport       `json:"details,omitempty"`
+}
+
+// Problems counts the issues in the report, so a scrape with none of them can
+// be published as is.
+func (r *ScrapeReport) Problems() int {
+    return len(r.FailedRows) + len(r.UnexpectedSections) + len(r.ShortRecipes) + len(r.Duplicates)
+}
+
+// Scrape reads the element list from source, which is either a URL such as
+// WikiURL or a saved copy of the wiki page: a single HTML file or a directory
+// whose .html files are parsed in name order.
+func Scrape(source string) ([]Element, ScrapeReport, error) {
+    report := ScrapeReport{
+        Source:             source,
+        FailedRows:         []FailedRow{},
+        UnexpectedSections: []UnexpectedSection{},
+        ShortRecipes:       []ShortRecipe{},
+        Duplicates:         []string{},
+    }
+
+    var pages []*goquery.Document
+    var err error
+    if IsURL(source) {
+        pages, err = fetchPage(source)
+    } else {
+        pages, err = readSnapshots(source)
+    }
+    if err != nil {
+        return nil, report, err
+    }
+    report.Pages = len(pages)
+
+    var elements []Element
+    for _, page := range pages {
+        elements = append(elements, parseElements(page, &report)...)
+    }
+
+    seen := make(map[string]int)
+    for _, element := range elements {
+        seen[element.Name]++
+        if seen[element.Name] == 2 {
+            report.Duplicates = append(report.Duplicates, element.Name)
+        }
+        report.Recipes += len(element.Recipes)
+    }
+    report.Elements = len(elements)
+
+    if len(elements) == 0 {
+        return nil, report, fmt.Errorf("no elements found in %s", source)
+    }
+    return elements, report, nil
+}
+
+// Load reads elements saved by an earlier scrape.
+func Load(filePath string) ([]Element, error) {
+    data, err := os.ReadFile(filePath)
+    if err != nil {
+        return nil, err
+    }
+
+    var elements []Element
+    if err := json.Unmarshal(data, &elements); err != nil {
+        return nil, err
+    }
+    return elements, nil
+}
+
+// IsURL reports whether source is fetched from the web rather than read from
+// saved pages.
+func IsURL(source string) bool {
+    return strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://")
+}
+
+// Save writes elements to filePath in the format the elements model reads.
+func Save(elements []Element, filePath string) error {
+    jsonData, err := json.MarshalIndent(elements, "", "  ")
+    if err != nil {
+        return fmt.Errorf("error marshalling to JSON: %w", err)
+    }
+
+    if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
+        return fmt.Errorf("error writing JSON to file: %w", err)
+    }
+    return nil
+}
+
+func fetchPage(url string) ([]*goquery.Document, error) {
     c := colly.NewCollector(
         colly.UserAgent("Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36"),
         colly.AllowedDomains("little-alchemy.fandom.com"),
         colly.AllowURLRevisit(),
     )
 
+    var page *goquery.Document
+    var parseErr error
+    c.OnResponse(func(r *colly.Response) {
+        page, parseErr = goquery.NewDocumentFromReader(bytes.NewReader(r.Body))
+    })
+
+    if err := c.Visit(url); err != nil {
+        return nil, err
+    }
+    if parseErr != nil {
+        return nil, parseErr
+    }
+    if page == nil {
+        return nil, fmt.Errorf("no response from %s", url)
+    }
+    return []*goquery.Document{page}, nil
+}
+
+func readSnapshots(path string) ([]*goquery.Document, error) {
+    info, err := os.Stat(path)
+    if err != nil {
+        return nil, err
+    }
+
+    files := []string{path}
+    if info.IsDir() {
+        entries, err := os.ReadDir(path)
+        if err != nil {
+            return nil, err
+        }
+        files = nil
+        for _, entry := range entries {
+            ext := strings.ToLower(filepath.Ext(entry.Name()))
+            if !entry.IsDir() && (ext == ".html" || ext == ".htm") {
+                files = append(files, filepath.Join(path, entry.Name()))
+            }
+        }
+        if len(files) == 0 {
+            return nil, fmt.Errorf("no HTML files in %s", path)
+        }
+    }
+
+    var pages []*goquery.Document
+    for _, file := range files {
+        f, err := os.Open(file)
+        if err != nil {
+            return nil, err
+        }
+        page, err := goquery.NewDocumentFromReader(f)
+        f.Close()
+        if err != nil {
+            return nil, fmt.Errorf("%s: %w", file, err)
+        }
+        pages = append(pages, page)
+    }
+    return pages, nil
+}
+
+// parseElements reads every element table of a wiki page, taking the tier of
+// each table from the section heading above it.
+func parseElements(page *goquery.Document, report *ScrapeReport) []Element {
     var elements []Element
     sectionTiers := make(map[string]int)
 
-    sectionCollector := c.Clone()
-    sectionCollector.OnHTML("h3 .mw-headline", func(e *colly.HTMLElement) {
-        sectionTitle := e.Text
-        sectionID := e.Attr("id")
+    page.Find("h3 .mw-headline").Each(func(_ int, headline *goquery.Selection) {
+        sectionTitle := headline.Text()
+        sectionID, _ := headline.Attr("id")
 
         var tier int
         switch sectionTitle {
@@ -44,17 +235,17 @@ func Scrape(filePath string) {
         default:
             if sectionTitle != "" {
                 rawSectionTitle := strings.Split(sectionTitle, " ")
+                tier = unparsedTier
                 if len(rawSectionTitle) == 3 {
-                    tierStr := rawSectionTitle[1]
-                    if t, err := strconv.Atoi(tierStr); err == nil {
+                    if t, err := strconv.Atoi(rawSectionTitle[1]); err == nil {
                         tier = t
-                    } else {
-                        fmt.Println("Error converting tier string to int:", err)
-                        tier = 999
                     }
-                } else {
-                    fmt.Println("Unexpected section title format:", sectionTitle)
-                    tier = 999
+                }
+                if tier == unparsedTier {
+                    report.UnexpectedSections = append(report.UnexpectedSections, UnexpectedSection{
+                        ID:    sectionID,
+                        Title: sectionTitle,
+                    })
                 }
             }
         }
@@ -62,25 +253,49 @@ func Scrape(filePath string) {
         sectionTiers[sectionID] = tier
     })
 
-    if err := sectionCollector.Visit("https://little-alchemy.fandom.com/wiki/Elements_(Little_Alchemy_2)"); err != nil {
-        log.Fatal(err)
-    }
-
-    c.OnHTML("h3", func(h3 *colly.HTMLElement) {
-        sectionID := h3.ChildAttr(".mw-headline", "id")
+    page.Find("h3").Each(func(_ int, h3 *goquery.Selection) {
+        sectionID, _ := h3.Find(".mw-headline").Attr("id")
         tier, exists := sectionTiers[sectionID]
         if !exists {
-            tier = 999
+            tier = unparsedTier
         }
+        section := strings.TrimSpace(h3.Text())
+        reported := exists
 
-        h3.DOM.NextUntil("h3").Each(func(i int, s *goquery.Selection) {
+        h3.NextUntil("h3").Each(func(i int, s *goquery.Selection) {
             if s.Is("table.list-table") {
-                s.Find("tr").Each(func(_ int, row *goquery.Selection) {
-                    if row.Find("td:nth-of-type(1)").Text() == "" {
+                if !reported {
+                    report.UnexpectedSections = append(report.UnexpectedSections, UnexpectedSection{
+                        ID:    sectionID,
+                        Title: section,
+                    })
+                    reported = true
+                }
+
+                s.Find("tr").Each(func(rowIndex int, row *goquery.Selection) {
+                    cells := row.Find("td")
+                    if cells.Length() == 0 {
+                        return
+                    }
+                    failed := func(reason string) {
+                        var texts []string
+                        cells.Each(func(_ int, cell *goquery.Selection) {
+                            texts = append(texts, strings.Join(strings.Fields(cell.Text()), " "))
+                        })
+                        report.FailedRows = append(report.FailedRows, FailedRow{
+                            Section: section,
+                            Row:     rowIndex,
+                            Text:    strings.Join(texts, " | "),
+                            Reason:  reason,
+                        })
+                    }
+
+                    name := strings.TrimSpace(row.Find("td:nth-of-type(1)").Text())
+                    if name == "" {
+                        failed("missing element name")
                         return
                     }
 
-                    name := row.Find("td:nth-of-type(1)").Text()
                     var recipes []Recipe
 
                     recipeText := row.Find("td:nth-of-type(2)").Text()
@@ -99,35 +314,32 @@ func Scrape(filePath string) {
                                 }
                                 if len(ingredients) >= 2 {
                                     recipes = append(recipes, Recipe{Ingredients: ingredients})
+                                } else {
+                                    report.ShortRecipes = append(report.ShortRecipes, ShortRecipe{
+                                        Element: name,
+                                        Text:    recipe,
+                                    })
                                 }
                             }
                         }
                     }
 
-                    if name != "" {
-                        elements = append(elements, Element{
-                            Name:    strings.TrimSpace(name),
-                            Tier:    tier,
-                            Recipes: recipes,
-                        })
+                    if tier != 0 && len(recipes) == 0 {
+                        failed("no recipes")
                     }
+
+                    nameCell := row.Find("td:nth-of-type(1)")
+                    elements = append(elements, Element{
+                        Name:    name,
+                        Tier:    tier,
+                        Recipes: recipes,
+                        Link:    elementLink(nameCell, name),
+                        IconURL: imageURL(nameCell.Find("img").First()),
+                    })
                 })
             }
         })
     })
 
-    if err := c.Visit("https://little-alchemy.fandom.com/wiki/Elements_(Little_Alchemy_2)"); err != nil {
-        log.Fatal(err)
-    }
-
-    jsonData, err := json.MarshalIndent(elements, "", "  ")
-    if err != nil {
-        log.Fatal("Error marshalling to JSON:", err)
-    }
-
-    if err := os.WriteFile(filePath, jsonData, 0644); err != nil {
-        log.Fatal("Error writing JSON to file:", err)
-    }
-
-    fmt.Println("Data successfully saved to", filePath)
-}
\ No newline at end of file
+    return elements
+}
@@ -0,0 +1,87 @@
+package scraper
+
+import (
+    "os"
+    "reflect"
+    "testing"
+)
+
+func TestScrapeSnapshot(t *testing.T) {
+    elements, report, err := Scrape("testdata/elements.html")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    tiers := make(map[string][]int)
+    var names []string
+    for _, element := range elements {
+        names = append(names, element.Name)
+        tiers[element.Name] = append(tiers[element.Name], element.Tier)
+    }
+    wantNames := []string{"Air", "Earth", "Fire", "Water", "Mud", "Dust", "Pressure", "Dragon", "Lava", "Mud", "Steam"}
+    if !reflect.DeepEqual(names, wantNames) {
+        t.Errorf("got elements %v, want %v", names, wantNames)
+    }
+    wantTiers := map[string][]int{
+        "Air": {0}, "Earth": {0}, "Fire": {0}, "Water": {0},
+        "Mud": {1, 2}, "Dust": {1}, "Pressure": {1},
+        "Dragon": {unparsedTier}, "Lava": {2}, "Steam": {unparsedTier},
+    }
+    if !reflect.DeepEqual(tiers, wantTiers) {
+        t.Errorf("got tiers %v, want %v", tiers, wantTiers)
+    }
+
+    dust := elements[5]
+    if want := []Recipe{{Ingredients: []string{"Earth", "Air"}}}; !reflect.DeepEqual(dust.Recipes, want) {
+        t.Errorf("got Dust recipes %v, want %v", dust.Recipes, want)
+    }
+    air := elements[0]
+    if air.Link != "https://little-alchemy.fandom.com/wiki/Air" {
+        t.Errorf("got Air link %q", air.Link)
+    }
+    if air.IconURL != "https://static.wikia.nocookie.net/little-alchemy/images/air.svg" {
+        t.Errorf("got Air icon %q", air.IconURL)
+    }
+
+    wantFailed := []FailedRow{
+        {Section: "Tier 1 elements", Row: 3, Text: " | Fire + Fire", Reason: "missing element name"},
+        {Section: "Tier 1 elements", Row: 4, Text: "Pressure | Coming soon", Reason: "no recipes"},
+    }
+    if !reflect.DeepEqual(report.FailedRows, wantFailed) {
+        t.Errorf("got failed rows %+v, want %+v", report.FailedRows, wantFailed)
+    }
+    wantSections := []UnexpectedSection{
+        {ID: "Myths_and_monsters", Title: "Myths and monsters"},
+        {ID: "", Title: "Unlisted"},
+    }
+    if !reflect.DeepEqual(report.UnexpectedSections, wantSections) {
+        t.Errorf("got unexpected sections %+v, want %+v", report.UnexpectedSections, wantSections)
+    }
+    wantShort := []ShortRecipe{{Element: "Dust", Text: "Air +"}}
+    if !reflect.DeepEqual(report.ShortRecipes, wantShort) {
+        t.Errorf("got short recipes %+v, want %+v", report.ShortRecipes, wantShort)
+    }
+    if want := []string{"Mud"}; !reflect.DeepEqual(report.Duplicates, want) {
+        t.Errorf("got duplicates %v, want %v", report.Duplicates, want)
+    }
+
+    if report.Pages != 1 || report.Elements != 11 || report.Recipes != 6 {
+        t.Errorf("got %d pages, %d elements, %d recipes, want 1, 11, 6", report.Pages, report.Elements, report.Recipes)
+    }
+    if got := report.Problems(); got != 6 {
+        t.Errorf("got %d problems, want 6", got)
+    }
+}
+
+func TestScrapeWithoutElements(t *testing.T) {
+    dir := t.TempDir()
+    if err := os.WriteFile(dir+"/empty.html", []byte("<html><body><h3>Nothing here</h3></body></html>"), 0644); err != nil {
+        t.Fatal(err)
+    }
+    if _, _, err := Scrape(dir); err == nil {
+        t.Error("scraping a page without elements succeeded")
+    }
+    if _, _, err := Scrape(t.TempDir()); err == nil {
+        t.Error("scraping a directory without HTML files succeeded")
+    }
+}
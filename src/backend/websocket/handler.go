@@ -2,6 +2,7 @@ package websocket
 
 import (
 	elementsController "backend/controllers"
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -11,12 +12,30 @@ import (
 
 type TreeMessage struct {
     Tree             *elementsController.TreeNode `json:"tree"`
-    NodesVisited     int64                        `json:"nodesVisited"`
+    DAG              *elementsController.RecipeDAG `json:"dag,omitempty"`
+    Chains           []*elementsController.Chain  `json:"chains,omitempty"`
+    Plans            []*elementsController.CraftingPlan `json:"plans,omitempty"`
+    NodesVisited     int64                        `json:"nodesVisited,omitempty"`
+    Stats            *elementsController.SearchStats `json:"stats,omitempty"`
     SearchDuration   time.Duration                `json:"searchDuration,omitempty"`
     ProgramDuration  time.Duration                `json:"programDuration,omitempty"`
+    Status           string                       `json:"status,omitempty"`
+    Error            string                       `json:"error,omitempty"`
     Done             bool                         `json:"done"`
 }
 
+// attachTree fills in either the nested tree or, for the "dag" format, only
+// the DAG nodes this connection has not been sent yet plus the root ID.
+func (msg *TreeMessage) attachTree(tree *elementsController.TreeNode, dag *elementsController.DAGBuilder) {
+    if dag == nil || tree == nil {
+        msg.Tree = tree
+        return
+    }
+
+    root := dag.Add(tree)
+    msg.DAG = &elementsController.RecipeDAG{Root: root, Nodes: dag.Flush()}
+}
+
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
@@ -33,11 +52,23 @@ func HandleTreeWebSocket(controller *elementsController.ElementController) http.
         defer conn.Close()
 
         var req struct {
+            Dataset        string `json:"dataset"`
+            Source         string `json:"source"`
             Target         string `json:"target"`
             Count          int    `json:"count"`
             UseBFS         bool   `json:"useBfs"`
+            Mode           string `json:"mode"`
             Delay          int    `json:"delay"`
             UseMultiThread bool   `json:"useMultiThread"`
+            Timeout        int    `json:"timeout"`
+            Format         string `json:"format"`
+            Workers        int      `json:"workers"`
+            Exclude        []string `json:"exclude"`
+            Require        []string `json:"require"`
+            MaxDepth       int      `json:"maxDepth"`
+            MaxTier        int      `json:"maxTier"`
+            AllowCycles    bool     `json:"allowCycles"`
+            IncludePlan    bool     `json:"includePlan"`
         }
 
         if err := conn.ReadJSON(&req); err != nil {
@@ -45,56 +76,115 @@ func HandleTreeWebSocket(controller *elementsController.ElementController) http.
             return
         }
 
+        var ctx context.Context
+        var cancel context.CancelFunc
+        if req.Timeout > 0 {
+            ctx, cancel = context.WithTimeout(r.Context(), time.Duration(req.Timeout)*time.Millisecond)
+        } else {
+            ctx, cancel = context.WithCancel(r.Context())
+        }
+        defer cancel()
+
+        go func() {
+            for {
+                var control struct {
+                    Type string `json:"type"`
+                }
+                if err := conn.ReadJSON(&control); err != nil {
+                    cancel()
+                    return
+                }
+                if control.Type == "cancel" {
+                    cancel()
+                }
+            }
+        }()
+
         treeChan := make(chan *elementsController.TreeNode, req.Count)
 
         var tree *elementsController.TreeNode
-        var nodesVisited int64
+        var chains []*elementsController.Chain
+        var stats *elementsController.SearchStats
         var searchDuration time.Duration
+        var searchErr error
 		startProgram := time.Now()
 
-        go func() {
+        opts := elementsController.SearchOptions{
+            Dataset:     req.Dataset,
+            Workers:     req.Workers,
+            Exclude:     req.Exclude,
+            Require:     req.Require,
+            MaxDepth:    req.MaxDepth,
+            MaxTier:     req.MaxTier,
+            AllowCycles: req.AllowCycles,
+        }
+
+        mode := req.Mode
+        if mode == "" {
+            mode = "dfs"
             if req.UseBFS {
-                if req.UseMultiThread {
-                    tree, nodesVisited, searchDuration = elementsController.StartBFSMulti(req.Target, req.Count, treeChan)
-                } else {
-                    tree, nodesVisited, searchDuration = elementsController.StartBFS(req.Target, req.Count, treeChan)
-                }
-            } else {
-                if req.UseMultiThread {
-                    tree, nodesVisited, searchDuration = elementsController.StartDFSMulti(req.Target, req.Count, treeChan)
-                } else {
-                    tree, nodesVisited, searchDuration = elementsController.StartDFS(req.Target, req.Count, treeChan)
-                }
+                mode = "bfs"
+            }
+        }
+
+        go func() {
+            switch mode {
+            case "bidirectional":
+                chains, stats, searchDuration, searchErr = elementsController.StartBidirectional(ctx, req.Dataset, req.Source, req.Target, req.Count)
+            default:
+                tree, stats, searchDuration, searchErr = elementsController.StartSearch(ctx, mode, req.UseMultiThread, req.Target, req.Count, opts, treeChan)
+            }
+            if searchErr != nil {
+                log.Println("Search stopped:", searchErr)
             }
             close(treeChan)
         }()
 
 		var delay time.Duration = time.Duration(req.Delay) * time.Millisecond
 
+        var dag *elementsController.DAGBuilder
+        if req.Format == "dag" {
+            dag = elementsController.NewDAGBuilder()
+        }
+
 		for intermediateTree := range treeChan {
-			time.Sleep(delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				continue
+			}
 
             msg := TreeMessage{
-                Tree:            intermediateTree,
-                NodesVisited:    nodesVisited,
-                SearchDuration:  searchDuration,
                 ProgramDuration: time.Since(startProgram),
                 Done:            false,
             }
+            msg.attachTree(intermediateTree, dag)
 
 			if err := conn.WriteJSON(msg); err != nil {
 				log.Println("Error sending intermediate result:", err)
+				cancel()
 				return
 			}
 		}
 
         finalMsg := TreeMessage{
-            Tree:            tree,
-            NodesVisited:    nodesVisited,
+            Chains:          chains,
+            NodesVisited:    stats.NodesVisited,
+            Stats:           stats,
             SearchDuration:  searchDuration,
             ProgramDuration: time.Since(startProgram),
+            Status:          elementsController.SearchStatus(searchErr),
             Done:            true,
         }
+        if searchErr != nil {
+            finalMsg.Error = searchErr.Error()
+        }
+        finalMsg.attachTree(tree, dag)
+        if req.IncludePlan {
+            finalMsg.Plans = elementsController.BuildCraftingPlans(tree)
+        }
 
         if err := conn.WriteJSON(finalMsg); err != nil {
             log.Println("Error sending final result:", err)